@@ -0,0 +1,433 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Hashes of converted inputs for -check hash, kept in the output folder
+const batchManifestName = ".lsf2lsx-batch.json"
+
+// One file to convert, Output is relative to the output folder
+type batchJob struct {
+	Input  string
+	Output string
+}
+
+type batchFailure struct {
+	Input string
+	Err   error
+}
+
+/*
+Converts every LSF, LSX and LSJ file under folders, glob matches and single files into a mirrored tree in the
+output folder, e.g. Public/Mod/Stats.lsf -> out/Public/Mod/Stats.lsx. Files already in the output format are
+left alone, as are hidden files and folders.
+
+Files are converted in parallel, and a file is skipped when its output is up to date: newer than the input with
+-check mtime, or made from the same contents and settings with -check hash (the hashes are kept in
+.lsf2lsx-batch.json in the output folder). Failures don't stop the batch, they're listed at the end.
+*/
+func runBatch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	var outputDir = flags.String("o", "", "Output folder (required)")
+	var outputFormat = flags.String("f", "lsx", "Output format: lsx, lsj or lsf")
+	var compressionMethod = flags.String("c", "lz4", "LSF output compression: none, zlib, lz4 or zstd")
+	var compressionLevel = flags.String("l", "default", "LSF output compression level: fast, default or max")
+	var outputOrder = flags.String("order", "sorted", "Output order: sorted (stable for diffs) or file (keeps the input's order, like Divine)")
+	var sortKeys = sortKeysFlag{}
+	flags.Var(sortKeys, "key", "Sort same-named nodes by an attribute first, as NodeName=Attribute (repeatable)")
+	var check = flags.String("check", "mtime", "Skip up to date outputs by: mtime, hash or none (convert everything)")
+	var workers = flags.Int("j", runtime.NumCPU(), "Number of files to convert in parallel")
+	var verbose = flags.Bool("v", false, "List each converted file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s batch [flags] -o <output-folder> <folder|glob|file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || *outputDir == "" {
+		flags.Usage()
+		return 1
+	}
+	switch *check {
+	case "mtime", "hash", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -check %q (expected mtime, hash or none)\n", *check)
+		return 1
+	}
+	order, err := parseOrder(*outputOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lsfOptions := lsf.DefaultWriterOptions
+	lsfOptions.Compression, err = parseCompressionFlags(*compressionMethod, *compressionLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lsfOptions.Order = order
+	lsfOptions.SortKeys = sortKeys
+	writeResource, err := resourceWriter(*outputFormat, order, sortKeys, nil, &lsfOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	outputExt := "." + strings.ToLower(*outputFormat)
+
+	jobs, failures := planBatch(flags.Args(), outputExt)
+
+	// Anything that changes the output invalidates the hashes
+	settings := fmt.Sprintf("%s %s %v %v", outputExt, *outputOrder, sortKeys, lsfOptions.Compression)
+	manifestPath := filepath.Join(*outputDir, batchManifestName)
+	manifest := map[string]string{}
+	if *check == "hash" {
+		manifest, err = readBatchManifest(manifestPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", manifestPath, err)
+			return 1
+		}
+	}
+
+	// The files are already spread over the CPUs, decompressing each one's sections in parallel would only add threads
+	if *workers > 1 {
+		lsfReaderOptions.Concurrency = 1
+	}
+
+	converter := &batchConverter{
+		OutputDir: *outputDir,
+		Workers:   *workers,
+		Check:     *check,
+		Settings:  settings,
+		Manifest:  manifest,
+		Write:     writeResource,
+	}
+	if *verbose {
+		converter.Logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+	converted, skipped, convertFailures := converter.Run(jobs)
+	failures = append(failures, convertFailures...)
+
+	if *check == "hash" {
+		err = writeBatchManifest(manifestPath, manifest)
+		if err != nil {
+			failures = append(failures, batchFailure{Input: manifestPath, Err: err})
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Converted %d files, %d up to date, %d failed\n", converted, skipped, len(failures))
+	if len(failures) == 0 {
+		return 0
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Input < failures[j].Input })
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "  %s: %v\n", failure.Input, failure.Err)
+	}
+	return 1
+}
+
+// Converts jobs on a pool of workers
+type batchConverter struct {
+	OutputDir string
+	Workers   int
+	// mtime, hash or none
+	Check string
+	// Output settings, hashed along with the input
+	Settings string
+	// Output path -> input hash for -check hash, updated with the files converted
+	Manifest map[string]string
+	Write    func(io.Writer, *resource.Resource) error
+	// Called for each converted file when set
+	Logf func(format string, args ...interface{})
+}
+
+func (c *batchConverter) Run(jobs []batchJob) (converted, skipped int, failures []batchFailure) {
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	queue := make(chan batchJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				outputPath := filepath.Join(c.OutputDir, job.Output)
+				key := filepath.ToSlash(job.Output)
+
+				var hash string
+				upToDate := false
+				var err error
+				switch c.Check {
+				case "mtime":
+					upToDate, err = newerThan(outputPath, job.Input)
+				case "hash":
+					hash, err = hashFile(job.Input, c.Settings)
+					if err == nil {
+						mu.Lock()
+						upToDate = c.Manifest[key] == hash
+						mu.Unlock()
+						upToDate = upToDate && fileExists(outputPath)
+					}
+				}
+				if err == nil && !upToDate {
+					err = convertBatchFile(job.Input, outputPath, c.Write)
+				}
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					failures = append(failures, batchFailure{Input: job.Input, Err: err})
+					delete(c.Manifest, key)
+				case upToDate:
+					skipped++
+				default:
+					converted++
+					if hash != "" {
+						c.Manifest[key] = hash
+					}
+					if c.Logf != nil {
+						c.Logf("%s -> %s", job.Input, outputPath)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	return converted, skipped, failures
+}
+
+// Expands the arguments into jobs. Globs and folders mirror the tree below them, single files go to the top.
+func planBatch(args []string, outputExt string) ([]batchJob, []batchFailure) {
+	var jobs []batchJob
+	var failures []batchFailure
+	outputs := make(map[string]string)
+
+	add := func(input, root string) {
+		relPath, err := filepath.Rel(root, input)
+		if err != nil {
+			failures = append(failures, batchFailure{Input: input, Err: err})
+			return
+		}
+		output := batchOutputPath(relPath, outputExt)
+		if other, ok := outputs[strings.ToLower(output)]; ok {
+			failures = append(failures, batchFailure{Input: input, Err: fmt.Errorf("%s is already converted from %s", output, other)})
+			return
+		}
+		outputs[strings.ToLower(output)] = input
+		jobs = append(jobs, batchJob{Input: input, Output: output})
+	}
+
+	walk := func(dir, root string) {
+		err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				failures = append(failures, batchFailure{Input: filePath, Err: err})
+				if entry != nil && entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filePath != dir && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.IsDir() && isBatchInput(filePath, outputExt) {
+				add(filePath, root)
+			}
+			return nil
+		})
+		if err != nil {
+			failures = append(failures, batchFailure{Input: dir, Err: err})
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		root := ""
+		if hasGlobMeta(arg) {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err == nil && len(matches) == 0 {
+				err = fmt.Errorf("no files match")
+			}
+			if err != nil {
+				failures = append(failures, batchFailure{Input: arg, Err: err})
+				continue
+			}
+			root = globRoot(arg)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				failures = append(failures, batchFailure{Input: match, Err: err})
+				continue
+			}
+			matchRoot := root
+			if matchRoot == "" {
+				matchRoot = match
+				if !info.IsDir() {
+					matchRoot = filepath.Dir(match)
+				}
+			}
+			if info.IsDir() {
+				walk(match, matchRoot)
+			} else if isBatchInput(match, outputExt) {
+				add(match, matchRoot)
+			} else {
+				failures = append(failures, batchFailure{Input: match, Err: fmt.Errorf("not an LSF, LSX or LSJ file to convert")})
+			}
+		}
+	}
+	return jobs, failures
+}
+
+// Stats.lsf -> Stats.lsx
+func batchOutputPath(relPath string, outputExt string) string {
+	return strings.TrimSuffix(relPath, filepath.Ext(relPath)) + outputExt
+}
+
+func isBatchInput(filename string, outputExt string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".lsf", ".lsx", ".lsj":
+		return ext != outputExt
+	}
+	return false
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// The folder above the first path element with wildcards, e.g. Public/*/Stats -> Public
+func globRoot(pattern string) string {
+	root := pattern
+	for hasGlobMeta(root) {
+		root = filepath.Dir(root)
+	}
+	return root
+}
+
+// Writes to a temporary file next to the output first, so a failed conversion never leaves half a file behind
+func convertBatchFile(input, output string, writeResource func(io.Writer, *resource.Resource) error) error {
+	res, _, err := readResource(input)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(output), 0o755)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = writeResource(file, res)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// Temp files are only readable by their owner, outputs get the usual mode or keep the one they had
+		mode := os.FileMode(0o644)
+		if info, statErr := os.Stat(output); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+		err = os.Chmod(file.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), output)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// Whether the output exists and was modified after the input
+func newerThan(output, input string) (bool, error) {
+	inputInfo, err := os.Stat(input)
+	if err != nil {
+		return false, err
+	}
+	outputInfo, err := os.Stat(output)
+	if err != nil {
+		return false, nil
+	}
+	return outputInfo.ModTime().After(inputInfo.ModTime()), nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+func hashFile(filename string, settings string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	io.WriteString(hash, settings+"\n")
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Output path -> hash of the input it was made from. A missing manifest is empty.
+func readBatchManifest(filename string) (map[string]string, error) {
+	manifest := map[string]string{}
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+func writeBatchManifest(filename string, manifest map[string]string) error {
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filename), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
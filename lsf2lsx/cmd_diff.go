@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/diff"
+)
+
+// Exits like diff(1): 0 when the files are the same, 1 when they differ and 2 on errors
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	var jsonOutput = flags.Bool("json", false, "Write the changes as JSON")
+	var sortKeys = sortKeysFlag{}
+	flags.Var(sortKeys, "key", "Identify same-named nodes by an attribute, as NodeName=Attribute (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [flags] <old-file> <new-file>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	oldResource, _, err := readResource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", flags.Arg(0), err)
+		return 2
+	}
+	newResource, _, err := readResource(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", flags.Arg(1), err)
+		return 2
+	}
+
+	changes := diff.Resources(oldResource, newResource, &diff.Options{Keys: sortKeys})
+	if *jsonOutput {
+		err = diff.WriteJSON(os.Stdout, changes)
+	} else {
+		err = diff.WriteText(os.Stdout, changes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing diff: %v\n", err)
+		return 2
+	}
+
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsx"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+/*
+Git clean filter: stdin (LSF, LSX or LSJ) -> canonical LSX on stdout, which is what gets committed.
+
+The LSX is sorted the same way as the textconv output, so the stored file only changes when the data does,
+no matter what order the editor wrote the LSF in. Empty files are passed through.
+*/
+func runClean(args []string) int {
+	flags := flag.NewFlagSet("clean", flag.ExitOnError)
+	var sortKeys = sortKeysFlag{}
+	flags.Var(sortKeys, "key", "Sort same-named nodes by an attribute first, as NodeName=Attribute (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s clean [flags] < input > output.lsx\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		return 1
+	}
+	if len(data) == 0 {
+		return passThrough(data)
+	}
+	res, _, err := decodeResource(bytes.NewReader(data), "stdin")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		return 1
+	}
+
+	return writeFilterOutput(func(w io.Writer) error {
+		return lsx.Write(w, res, &lsx.WriterOptions{SortKeys: sortKeys})
+	})
+}
+
+/*
+Git smudge filter: LSX on stdin -> LSF on stdout, for the working tree.
+
+The LSF is written in the order of the LSX, so cleaning it again gives back the same LSX. Input that's
+already an LSF (committed before the filter was set up) is passed through untouched, as are empty files.
+*/
+func runSmudge(args []string) int {
+	flags := flag.NewFlagSet("smudge", flag.ExitOnError)
+	var compressionMethod = flags.String("c", "lz4", "LSF compression: none, zlib, lz4 or zstd")
+	var compressionLevel = flags.String("l", "default", "LSF compression level: fast, default or max")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s smudge [flags] < input.lsx > output.lsf\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	compressionFlags, err := parseCompressionFlags(*compressionMethod, *compressionLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		return 1
+	}
+	if len(data) == 0 || bytes.HasPrefix(data, lsf.Signature) {
+		return passThrough(data)
+	}
+
+	res, _, err := decodeResource(bytes.NewReader(data), "stdin")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		return 1
+	}
+
+	return writeFilterOutput(func(w io.Writer) error {
+		return lsf.Write(w, res, &lsf.WriterOptions{
+			Version:     lsf.VersionMax,
+			Compression: compressionFlags,
+			Order:       resource.OrderFile,
+		})
+	})
+}
+
+func passThrough(data []byte) int {
+	return writeFilterOutput(func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func writeFilterOutput(write func(w io.Writer) error) int {
+	output := bufio.NewWriter(os.Stdout)
+	err := write(output)
+	if err == nil {
+		err = output.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing stdout: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/loca"
+)
+
+/*
+Converts localization files between the binary .loca format and XML. Either one is accepted as input.
+
+XML on stdout is the default, so this works as a git textconv for .loca files.
+*/
+func runLoca(args []string) int {
+	flags := flag.NewFlagSet("loca", flag.ExitOnError)
+	var outputFile = flags.String("o", "", "Output file path (optional, defaults to stdout)")
+	var outputFormat = flags.String("f", "xml", "Output format: xml or loca")
+	var outputOrder = flags.String("order", "sorted", "Output order: sorted (by handle) or file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s loca [flags] <input.loca|input.xml|->\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	order, err := parseOrder(*outputOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var write func(io.Writer, *loca.Resource, *loca.WriterOptions) error
+	switch strings.ToLower(*outputFormat) {
+	case "xml":
+		write = loca.WriteXML
+	case "loca":
+		write = loca.WriteBinary
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (expected xml or loca)\n", *outputFormat)
+		return 1
+	}
+
+	input, err := openInput(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	res, err := loca.Read(input)
+	input.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	err = writeOutput(*outputFile, func(w io.Writer) error {
+		return write(w, res, &loca.WriterOptions{Order: order})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *outputFormat, err)
+		return 1
+	}
+	return 0
+}
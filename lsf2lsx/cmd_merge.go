@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/merge"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+/*
+Git merge driver, configured as `lsf2lsx merge %O %A %B %P`. The merged result replaces %A in our
+file's format (an LSF keeps its version and compression) and in our order.
+
+Follows the driver contract: exits 0 for a clean merge and 1 when there are conflicts, which are listed
+on stderr while %A holds the merge with our side of each conflict. On errors %A is left alone and we exit
+with 2, so git still treats the file as conflicted.
+*/
+func runMerge(args []string) int {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	var sortKeys = sortKeysFlag{}
+	flags.Var(sortKeys, "key", "Identify same-named nodes by an attribute, as NodeName=Attribute (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s merge [flags] <base> <ours> <theirs> [path]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "As a git merge driver: %s merge %%O %%A %%B %%P\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 3 && flags.NArg() != 4 {
+		flags.Usage()
+		return 2
+	}
+	basePath, ourPath, theirPath := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	displayPath := ourPath
+	if flags.NArg() == 4 {
+		displayPath = flags.Arg(3)
+	}
+
+	base, _, err := readResource(basePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading base of %s: %v\n", displayPath, err)
+		return 2
+	}
+	ours, lsfReader, err := readResource(ourPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading our %s: %v\n", displayPath, err)
+		return 2
+	}
+	theirs, _, err := readResource(theirPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading their %s: %v\n", displayPath, err)
+		return 2
+	}
+	format, err := detectFileFormat(ourPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading our %s: %v\n", displayPath, err)
+		return 2
+	}
+
+	conflicts := merge.Resources(base, ours, theirs, &merge.Options{Keys: sortKeys})
+
+	lsfOptions := lsf.DefaultWriterOptions
+	if lsfReader != nil {
+		lsfOptions.Version = lsfReader.Version()
+		lsfOptions.Compression = lsfReader.CompressionFlags()
+	}
+	lsfOptions.Order = resource.OrderFile
+	writeResource, err := resourceWriter(format, resource.OrderFile, sortKeys, nil, &lsfOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	// Only replace our file once the whole merge has been written
+	var merged bytes.Buffer
+	err = writeResource(&merged, ours)
+	if err == nil {
+		err = os.WriteFile(ourPath, merged.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing merged %s: %v\n", displayPath, err)
+		return 2
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "Merge conflicts in %s (kept our side):\n", displayPath)
+		for _, conflict := range conflicts {
+			fmt.Fprintf(os.Stderr, "  %s\n", conflict)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+/*
+Packs a mod folder into an LSPK v18 archive, then reads the archive back to check it.
+
+Files keep their path relative to the folder. Files named *.lsf.lsx or *.lsf.lsj are converted to *.lsf,
+the usual convention for keeping LSF sources as text, and -convert converts every other .lsx too (except
+meta.lsx, which the game reads as LSX). Hidden files and folders (.git etc) are skipped.
+*/
+func runPack(args []string) int {
+	flags := flag.NewFlagSet("pack", flag.ExitOnError)
+	var outputFile = flags.String("o", "", "Output .pak file path (required)")
+	var compressionMethod = flags.String("c", "lz4", "Compression: none, zlib, lz4 or zstd")
+	var compressionLevel = flags.String("l", "default", "Compression level: fast, default or max")
+	var priority = flags.Uint("priority", 0, "Load order priority of the archive")
+	var convertAll = flags.Bool("convert", false, "Convert all .lsx files (apart from meta.lsx) to .lsf")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s pack [flags] -o <output.pak> <mod-folder>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *outputFile == "" {
+		flags.Usage()
+		return 1
+	}
+	root := flags.Arg(0)
+
+	compressionFlags, err := parseCompressionFlags(*compressionMethod, *compressionLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *priority > 255 {
+		fmt.Fprintf(os.Stderr, "Error: priority must be 0-255\n")
+		return 1
+	}
+
+	writer, err := pak.Create(*outputFile, &pak.WriterOptions{Compression: compressionFlags, Priority: uint8(*priority)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outputFile, err)
+		return 1
+	}
+
+	// The archive can be inside the folder, it mustn't pack itself
+	outputInfo, err := os.Stat(*outputFile)
+	if err != nil {
+		writer.Close()
+		os.Remove(*outputFile)
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", *outputFile, err)
+		return 1
+	}
+
+	// Hashes of what went in, to check the archive against
+	packed := make(map[string][sha256.Size]byte)
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && os.SameFile(info, outputInfo) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		name, data, err := packFile(filePath, filepath.ToSlash(relPath), *convertAll)
+		if err != nil {
+			return err
+		}
+		packed[name] = sha256.Sum256(data)
+		return writer.WriteFile(name, data)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*outputFile)
+		fmt.Fprintf(os.Stderr, "Error packing %s: %v\n", root, err)
+		return 1
+	}
+
+	problems := verifyPak(*outputFile, packed)
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "Packed %s, but it failed verification:\n", *outputFile)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  %v\n", problem)
+		}
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Packed %d files into %s\n", len(packed), *outputFile)
+	return 0
+}
+
+// Returns the path in the archive and the contents, converting LSX/LSJ sources to LSF
+func packFile(filePath string, name string, convertAll bool) (string, []byte, error) {
+	lowerName := strings.ToLower(name)
+	convert := strings.HasSuffix(lowerName, ".lsf.lsx") || strings.HasSuffix(lowerName, ".lsf.lsj")
+	if convert {
+		name = name[:len(name)-len(".lsx")]
+	} else if convertAll && path.Ext(lowerName) == ".lsx" && path.Base(lowerName) != "meta.lsx" {
+		convert = true
+		name = name[:len(name)-len(".lsx")] + ".lsf"
+	}
+
+	if !convert {
+		data, err := os.ReadFile(filePath)
+		return name, data, err
+	}
+
+	res, _, err := readResource(filePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	var data bytes.Buffer
+	opts := lsf.DefaultWriterOptions
+	opts.Order = resource.OrderFile
+	err = lsf.Write(&data, res, &opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return name, data.Bytes(), nil
+}
+
+// Checks the archive's structure, and that it holds exactly the expected files when given their hashes
+func verifyPak(filename string, expected map[string][sha256.Size]byte) []error {
+	archive, err := pak.Open(filename)
+	if err != nil {
+		return []error{err}
+	}
+	defer archive.Close()
+
+	problems := archive.Verify()
+	if expected == nil || len(problems) > 0 {
+		return problems
+	}
+
+	if len(archive.Entries()) != len(expected) {
+		problems = append(problems, fmt.Errorf("archive has %d files, expected %d", len(archive.Entries()), len(expected)))
+	}
+	for name, hash := range expected {
+		data, err := archive.ReadFile(name)
+		if err != nil {
+			problems = append(problems, err)
+		} else if sha256.Sum256(data) != hash {
+			problems = append(problems, fmt.Errorf("%s: contents differ from the packed file", name))
+		}
+	}
+	return problems
+}
+
+// Lists the files in an archive with their sizes and compression
+func runList(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list <archive.pak>\n", os.Args[0])
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	archive, err := pak.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer archive.Close()
+
+	fmt.Printf("%12s %12s %-5s %s\n", "Size", "Packed", "Comp", "Name")
+	for _, entry := range archive.Entries() {
+		fmt.Printf("%12d %12d %-5s %s\n", entry.Size(), entry.SizeOnDisk, entry.Compression.Method(), entry.Name)
+	}
+	return 0
+}
+
+// Checks an archive and decompresses all its files, exits with 1 if anything is wrong
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify <archive.pak>\n", os.Args[0])
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	problems := verifyPak(flags.Arg(0), nil)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%v\n", problem)
+		}
+		return 1
+	}
+	fmt.Printf("%s: OK\n", flags.Arg(0))
+	return 0
+}
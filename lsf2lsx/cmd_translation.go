@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/loca"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/translation"
+)
+
+/*
+Exports the translated strings used by a mod for translators, as PO or XLIFF.
+
+Every LSF, LSX and LSJ file under the given folders (or the given files) is scanned for translated string
+handles, and their source text comes from the -source localization files. Existing translations can be
+filled in with -target, so translators only see what's new.
+*/
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	var outputFile = flags.String("o", "", "Output file path (optional, defaults to stdout)")
+	var outputFormat = flags.String("f", "", "Output format: po or xliff (defaults to xliff for .xlf/.xliff outputs, po otherwise)")
+	var sourceFiles = filesFlag{}
+	flags.Var(&sourceFiles, "source", "Localization file (.loca or XML) with the source texts (repeatable)")
+	var targetFiles = filesFlag{}
+	flags.Var(&targetFiles, "target", "Localization file with existing translations (repeatable)")
+	var sourceLanguage = flags.String("source-lang", "en", "Language code of the source texts")
+	var targetLanguage = flags.String("lang", "", "Language code of the translation, e.g. fr")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export [flags] -source <english.loca> <mod-folder|file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || len(sourceFiles) == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	format := strings.ToLower(*outputFormat)
+	if format == "" {
+		format = "po"
+		if ext := strings.ToLower(filepath.Ext(*outputFile)); ext == ".xlf" || ext == ".xliff" {
+			format = "xliff"
+		}
+	}
+	var write func(io.Writer, *translation.Catalog) error
+	switch format {
+	case "po":
+		write = translation.WritePO
+	case "xliff":
+		write = translation.WriteXLIFF
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (expected po or xliff)\n", *outputFormat)
+		return 1
+	}
+
+	catalog := translation.NewCatalog(*sourceLanguage, *targetLanguage)
+	for _, root := range flags.Args() {
+		err := addResources(catalog, root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	for _, filename := range sourceFiles {
+		texts, err := loca.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", filename, err)
+			return 1
+		}
+		catalog.SetSources(texts)
+	}
+	for _, filename := range targetFiles {
+		texts, err := loca.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", filename, err)
+			return 1
+		}
+		catalog.SetTargets(texts)
+	}
+	catalog.Sort()
+
+	missing := 0
+	for _, unit := range catalog.Units {
+		if unit.Source == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d of %d handles have no source text\n", missing, len(catalog.Units))
+	}
+
+	err := writeOutput(*outputFile, func(w io.Writer) error {
+		return write(w, catalog)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", format, err)
+		return 1
+	}
+	return 0
+}
+
+// Adds the resources in a folder (skipping hidden files and folders), or a single resource file
+func addResources(catalog *translation.Catalog, root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		res, _, err := readResource(root)
+		if err != nil {
+			return fmt.Errorf("%s: %w", root, err)
+		}
+		catalog.AddResource(res, filepath.ToSlash(root))
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(filePath)) {
+		case ".lsf", ".lsx", ".lsj":
+		default:
+			return nil
+		}
+
+		res, _, err := readResource(filePath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		catalog.AddResource(res, filepath.ToSlash(relPath))
+		return nil
+	})
+}
+
+/*
+Imports translated PO or XLIFF files as .loca files, keeping the handle versions they were exported with.
+
+With a single input -o can name the output file. Otherwise -o is a Localization folder, and each input is
+written to <folder>/<Language>/<language>.loca using the language in its header.
+*/
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	var output = flags.String("o", "", "Output .loca/.xml file, or Localization folder (required)")
+	var outputFormat = flags.String("f", "loca", "Output format: loca or xml")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import [flags] -o <output.loca|Localization-folder> <translation.po|.xlf>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || *output == "" {
+		flags.Usage()
+		return 1
+	}
+
+	var write func(io.Writer, *loca.Resource, *loca.WriterOptions) error
+	var ext string
+	switch strings.ToLower(*outputFormat) {
+	case "loca":
+		write, ext = loca.WriteBinary, ".loca"
+	case "xml":
+		write, ext = loca.WriteXML, ".xml"
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (expected loca or xml)\n", *outputFormat)
+		return 1
+	}
+
+	outputExt := strings.ToLower(filepath.Ext(*output))
+	singleFile := outputExt == ".loca" || outputExt == ".xml"
+	if singleFile && flags.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Error: -o has to be a folder when importing several files\n")
+		return 1
+	}
+
+	for _, inputFile := range flags.Args() {
+		catalog, err := readCatalog(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", inputFile, err)
+			return 1
+		}
+
+		outputFile := *output
+		if !singleFile {
+			folder, ok := translation.LanguageFolder(catalog.TargetLanguage)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: %s: unknown language %q, use -o to name the output file\n", inputFile, catalog.TargetLanguage)
+				return 1
+			}
+			outputFile = filepath.Join(*output, folder, strings.ToLower(folder)+ext)
+			err = os.MkdirAll(filepath.Dir(outputFile), 0o755)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+
+		translated := 0
+		for _, unit := range catalog.Units {
+			if unit.Target != "" {
+				translated++
+			}
+		}
+
+		err = writeOutput(outputFile, func(w io.Writer) error {
+			return write(w, catalog.Loca(), nil)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", outputFile, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d of %d texts translated\n", outputFile, translated, len(catalog.Units))
+	}
+	return 0
+}
+
+// PO or XLIFF, told apart by the content
+func readCatalog(filename string) (*translation.Catalog, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return translation.ReadXLIFF(bytes.NewReader(data))
+	}
+	return translation.ReadPO(bytes.NewReader(data))
+}
+
+// Writes to a file, or stdout without a file name
+func writeOutput(filename string, write func(io.Writer) error) error {
+	if filename == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
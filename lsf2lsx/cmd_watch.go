@@ -0,0 +1,291 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+/*
+Keeps a converted mirror of folders up to date, like batch but continuously. Outdated files are converted when
+it starts, then files are converted again as they change and their copies removed when they (or the folders
+they're in) are deleted or moved away.
+
+Changes come from inotify on Linux, and from polling the folders elsewhere or with -poll (for network drives and
+the Windows drives under WSL, which don't send notifications). A file is converted once it hasn't changed for
+the debounce time, so a tool writing it in several steps only causes one conversion. Failures are logged and
+the watcher keeps going, until it's interrupted.
+*/
+func runWatch(args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	var outputDir = flags.String("o", "", "Output folder (required)")
+	var outputFormat = flags.String("f", "lsx", "Output format: lsx, lsj or lsf")
+	var compressionMethod = flags.String("c", "lz4", "LSF output compression: none, zlib, lz4 or zstd")
+	var compressionLevel = flags.String("l", "default", "LSF output compression level: fast, default or max")
+	var outputOrder = flags.String("order", "sorted", "Output order: sorted (stable for diffs) or file (keeps the input's order, like Divine)")
+	var sortKeys = sortKeysFlag{}
+	flags.Var(sortKeys, "key", "Sort same-named nodes by an attribute first, as NodeName=Attribute (repeatable)")
+	var poll = flags.Bool("poll", false, "Poll the folders for changes instead of using file notifications")
+	var interval = flags.Duration("interval", time.Second, "How often to poll for changes")
+	var debounce = flags.Duration("debounce", 500*time.Millisecond, "How long a file has to stay unchanged before it's converted")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s watch [flags] -o <output-folder> <folder>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || *outputDir == "" {
+		flags.Usage()
+		return 1
+	}
+	if *interval <= 0 || *debounce < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -interval must be positive and -debounce can't be negative\n")
+		return 1
+	}
+	roots := flags.Args()
+	for _, root := range roots {
+		info, err := os.Stat(root)
+		if err == nil && !info.IsDir() {
+			err = fmt.Errorf("%s is not a folder", root)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	order, err := parseOrder(*outputOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lsfOptions := lsf.DefaultWriterOptions
+	lsfOptions.Compression, err = parseCompressionFlags(*compressionMethod, *compressionLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lsfOptions.Order = order
+	lsfOptions.SortKeys = sortKeys
+	writeResource, err := resourceWriter(*outputFormat, order, sortKeys, nil, &lsfOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Syncs convert a file per CPU, like batch
+	lsfReaderOptions.Concurrency = 1
+
+	logger := log.New(os.Stderr, "", log.Ltime)
+	m := &mirror{
+		roots:     roots,
+		outputDir: *outputDir,
+		outputExt: "." + strings.ToLower(*outputFormat),
+		write:     writeResource,
+		logger:    logger,
+	}
+
+	// Start watching first, so nothing changed during the initial sync is missed
+	var watcher fileWatcher
+	if !*poll {
+		watcher, err = newNativeWatcher(roots)
+		if err != nil && err != errNoNativeWatcher {
+			logger.Printf("Polling for changes instead: %v", err)
+		}
+	}
+	if watcher == nil {
+		watcher = newPollWatcher(roots, *interval)
+	}
+	defer watcher.Close()
+
+	for _, root := range roots {
+		m.sync(root)
+	}
+	logger.Printf("Watching %s", strings.Join(roots, ", "))
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	// Path -> time of its last change, converted once it's been quiet for the debounce time
+	pending := make(map[string]time.Time)
+	tick := *debounce / 2
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case path, ok := <-watcher.Changes():
+			if !ok {
+				logger.Printf("Stopped watching")
+				return 1
+			}
+			pending[path] = time.Now()
+
+		case err := <-watcher.Errors():
+			logger.Printf("Error: %v", err)
+
+		case now := <-ticker.C:
+			for path, changed := range pending {
+				if now.Sub(changed) >= *debounce {
+					delete(pending, path)
+					m.update(path)
+				}
+			}
+
+		case <-interrupt:
+			return 0
+		}
+	}
+}
+
+// Converts files below the roots into the same tree in the output folder
+type mirror struct {
+	roots     []string
+	outputDir string
+	outputExt string
+	write     func(io.Writer, *resource.Resource) error
+	logger    *log.Logger
+}
+
+// Converts the outdated files in a folder, like batch does
+func (m *mirror) sync(dir string) {
+	root, _ := m.root(dir)
+	jobs, failures := planBatch([]string{root}, m.outputExt)
+	jobs = jobsUnder(jobs, dir)
+
+	converter := &batchConverter{
+		OutputDir: m.outputDir,
+		Workers:   runtime.NumCPU(),
+		Check:     "mtime",
+		Write:     m.write,
+		Logf:      m.logger.Printf,
+	}
+	_, _, convertFailures := converter.Run(jobs)
+	for _, failure := range append(failures, convertFailures...) {
+		if isUnder(failure.Input, dir) {
+			m.logger.Printf("Failed: %s: %v", failure.Input, failure.Err)
+		}
+	}
+}
+
+// Converts a changed file again, or removes the copy of a deleted one
+func (m *mirror) update(path string) {
+	root, relPath := m.root(path)
+	if root == "" || isHiddenPath(relPath) {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		m.remove(relPath, path)
+		return
+	}
+	if err != nil {
+		m.logger.Printf("Failed: %v", err)
+		return
+	}
+
+	if info.IsDir() {
+		m.sync(path)
+		return
+	}
+	if !isBatchInput(path, m.outputExt) {
+		return
+	}
+	// Already converted when the folder it's in was synced
+	output := filepath.Join(m.outputDir, batchOutputPath(relPath, m.outputExt))
+	if upToDate, _ := newerThan(output, path); upToDate {
+		return
+	}
+	err = convertBatchFile(path, output, m.write)
+	if err != nil {
+		m.logger.Printf("Failed: %s: %v", path, err)
+		return
+	}
+	m.logger.Printf("%s -> %s", path, output)
+}
+
+// Removes the copy of a deleted file, or everything copied from a deleted folder
+func (m *mirror) remove(relPath string, path string) {
+	if relPath == "." {
+		// The watched folder itself, its copies are left alone
+		return
+	}
+
+	output := filepath.Join(m.outputDir, relPath)
+	if info, err := os.Stat(output); err == nil && info.IsDir() {
+		err = os.RemoveAll(output)
+		if err != nil {
+			m.logger.Printf("Failed: %v", err)
+			return
+		}
+		m.logger.Printf("Removed %s", output)
+	} else if isBatchInput(path, m.outputExt) {
+		output = filepath.Join(m.outputDir, batchOutputPath(relPath, m.outputExt))
+		if os.Remove(output) != nil {
+			return
+		}
+		m.logger.Printf("Removed %s", output)
+	} else {
+		return
+	}
+
+	// Folders emptied by that, so a folder moved away doesn't stay behind when polling, which only sees its files
+	for dir := filepath.Dir(output); isUnder(dir, m.outputDir) && dir != filepath.Clean(m.outputDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+}
+
+// The watched folder a path is in, and the path relative to it
+func (m *mirror) root(path string) (string, string) {
+	for _, root := range m.roots {
+		if relPath, err := filepath.Rel(root, path); err == nil && isLocalPath(relPath) {
+			return root, relPath
+		}
+	}
+	return "", ""
+}
+
+func jobsUnder(jobs []batchJob, dir string) []batchJob {
+	result := jobs[:0]
+	for _, job := range jobs {
+		if isUnder(job.Input, dir) {
+			result = append(result, job)
+		}
+	}
+	return result
+}
+
+func isUnder(path, dir string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	return err == nil && isLocalPath(relPath)
+}
+
+func isLocalPath(relPath string) bool {
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+func isHiddenPath(relPath string) bool {
+	for _, name := range strings.Split(relPath, string(filepath.Separator)) {
+		if strings.HasPrefix(name, ".") && name != "." {
+			return true
+		}
+	}
+	return false
+}
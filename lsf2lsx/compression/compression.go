@@ -0,0 +1,217 @@
+// Package compression implements the compression methods used by LSF files and pak archives.
+package compression
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Method represents the compression method
+type Method uint8
+
+const (
+	None Method = iota
+	Zlib
+	LZ4
+	Zstd
+)
+
+func (m Method) String() string {
+	switch m {
+	case None:
+		return "none"
+	case Zlib:
+		return "zlib"
+	case LZ4:
+		return "lz4"
+	case Zstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(m))
+}
+
+// Compression levels stored in the upper 4 bits of Flags (same values as lslib)
+const (
+	LevelFast    = 1
+	LevelDefault = 2
+	LevelMax     = 4
+)
+
+// Flags is a bitfield for compression settings
+type Flags uint8
+
+func MakeFlags(method Method, level uint8) Flags {
+	return Flags(uint8(method)&0x0f | (level&0x0f)<<4)
+}
+
+func (f Flags) Method() Method {
+	return Method(f & 0x0f)
+}
+
+func (f Flags) Level() uint8 {
+	return uint8((f >> 4) & 0x0f)
+}
+
+// Decompress decompresses data using the specified method
+func Decompress(compressed []byte, decompressedSize int, flags Flags, chunked bool) ([]byte, error) {
+	method := flags.Method()
+
+	switch method {
+	case None:
+		return compressed, nil
+
+	case Zlib:
+		reader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		decompressed := make([]byte, decompressedSize)
+		_, err = io.ReadFull(reader, decompressed)
+		if err != nil {
+			return nil, fmt.Errorf("zlib decompression size mismatch: expected %d: %w", decompressedSize, err)
+		}
+		return decompressed, nil
+
+	case LZ4:
+		if chunked {
+			reader := lz4.NewReader(bytes.NewReader(compressed))
+			decompressed := make([]byte, decompressedSize)
+			_, err := io.ReadFull(reader, decompressed)
+			if err != nil {
+				return nil, fmt.Errorf("LZ4 decompression size mismatch: expected %d: %w", decompressedSize, err)
+			}
+			return decompressed, nil
+		} else {
+			decompressed := make([]byte, decompressedSize)
+			n, err := lz4.UncompressBlock(compressed, decompressed)
+			if err != nil {
+				return nil, err
+			}
+			if n != decompressedSize {
+				return nil, fmt.Errorf("LZ4 decompression size mismatch: expected %d, got %d", decompressedSize, n)
+			}
+			return decompressed, nil
+		}
+
+	case Zstd:
+		// Streamed into a buffer of the expected size, so bad data can't make it allocate more than that
+		reader := zstd.NewReader(bytes.NewReader(compressed))
+		defer reader.Close()
+
+		decompressed := make([]byte, decompressedSize)
+		_, err := io.ReadFull(reader, decompressed)
+		if err != nil {
+			return nil, fmt.Errorf("Zstd decompression size mismatch: expected %d: %w", decompressedSize, err)
+		}
+		n, err := reader.Read(make([]byte, 1))
+		if n > 0 {
+			return nil, fmt.Errorf("Zstd decompression size mismatch: more than the expected %d bytes", decompressedSize)
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return decompressed, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported compression method: %d", method)
+	}
+}
+
+// Compress compresses data using the specified method (the reverse of Decompress)
+func Compress(data []byte, flags Flags, chunked bool) ([]byte, error) {
+	method := flags.Method()
+	level := flags.Level()
+
+	switch method {
+	case None:
+		return data, nil
+
+	case Zlib:
+		zlibLevel := zlib.DefaultCompression
+		switch level {
+		case LevelFast:
+			zlibLevel = zlib.BestSpeed
+		case LevelMax:
+			zlibLevel = zlib.BestCompression
+		}
+
+		var buf bytes.Buffer
+		writer, err := zlib.NewWriterLevel(&buf, zlibLevel)
+		if err != nil {
+			return nil, err
+		}
+		_, err = writer.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case LZ4:
+		lz4Level := lz4.Level9
+		if level == LevelFast {
+			lz4Level = lz4.Fast
+		}
+
+		if chunked {
+			var buf bytes.Buffer
+			writer := lz4.NewWriter(&buf)
+			err := writer.Apply(lz4.BlockSizeOption(lz4.Block64Kb), lz4.CompressionLevelOption(lz4Level))
+			if err != nil {
+				return nil, err
+			}
+			_, err = writer.Write(data)
+			if err != nil {
+				return nil, err
+			}
+			err = writer.Close()
+			if err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		} else {
+			// A destination of CompressBlockBound size always fits, even if the data is incompressible
+			compressed := make([]byte, lz4.CompressBlockBound(len(data)))
+			var n int
+			var err error
+			if level == LevelFast {
+				compressor := &lz4.Compressor{}
+				n, err = compressor.CompressBlock(data, compressed)
+			} else {
+				compressor := &lz4.CompressorHC{Level: lz4Level}
+				if level == LevelMax {
+					// No limit on the search depth. Only blocks can do this, frames top out at Level9
+					compressor.Level = 0
+				}
+				n, err = compressor.CompressBlock(data, compressed)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return compressed[:n], nil
+		}
+
+	case Zstd:
+		zstdLevel := zstd.DefaultCompression
+		switch level {
+		case LevelFast:
+			zstdLevel = zstd.BestSpeed
+		case LevelMax:
+			zstdLevel = zstd.BestCompression
+		}
+		return zstd.CompressLevel(nil, data, zstdLevel)
+
+	default:
+		return nil, fmt.Errorf("unsupported compression method: %d", method)
+	}
+}
@@ -0,0 +1,29 @@
+package compression
+
+import (
+	"testing"
+)
+
+func FuzzDecompress(f *testing.F) {
+	data := []byte("LSF sections are mostly names, node tables and values, names, node tables and values")
+	for _, method := range []Method{None, Zlib, LZ4, Zstd} {
+		for _, chunked := range []bool{false, true} {
+			flags := MakeFlags(method, LevelDefault)
+			compressed, err := Compress(data, flags, chunked)
+			if err != nil {
+				f.Fatalf("compressing seed with %s: %v", method, err)
+			}
+			f.Add(compressed, uint16(len(data)), uint8(flags), chunked)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, compressed []byte, size uint16, flags uint8, chunked bool) {
+		decompressed, err := Decompress(compressed, int(size), Flags(flags), chunked)
+		if err != nil {
+			return
+		}
+		if Flags(flags).Method() != None && len(decompressed) != int(size) {
+			t.Fatalf("decompressed %d bytes, expected %d", len(decompressed), size)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+// Package diff compares two resources structurally, matching nodes by their identity rather than by position.
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Kind of change
+type Kind string
+
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Modified Kind = "modified"
+)
+
+// Change is a node or attribute that differs between the two resources.
+// Node changes have no Attribute, attribute changes name the attribute of the node at Path.
+type Change struct {
+	Kind      Kind   `json:"kind"`
+	Path      string `json:"path"`
+	Attribute string `json:"attribute,omitempty"`
+	// Type of the attribute (the new one, unless it was removed)
+	Type string `json:"type,omitempty"`
+	// Set when a modified attribute also changed type
+	OldType string `json:"oldType,omitempty"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// Options configures how nodes are matched up
+type Options struct {
+	// Node name -> attribute identifying nodes of that name, on top of the nodes' own keys (see resource.Sorter)
+	Keys map[string]string
+}
+
+/*
+Resources returns the changes needed to turn a into b.
+
+Same-named siblings are matched in three passes:
+ 1. By identity: the key attribute or a GUID (see resource.Sorter.Identity)
+ 2. Nodes without an identity that are unchanged, by their hash
+ 3. Whatever is left without an identity, in sorted order
+
+Anything still unmatched was added or removed. Matched nodes that differ are reported attribute by
+attribute, so moving a node around without changing it doesn't show up at all.
+*/
+func Resources(a, b *resource.Resource, opts *Options) []Change {
+	if opts == nil {
+		opts = &Options{}
+	}
+	d := &differ{sorter: &resource.Sorter{Keys: opts.Keys}}
+
+	oldVersion := formatVersion(a.Metadata)
+	newVersion := formatVersion(b.Metadata)
+	if oldVersion != newVersion {
+		d.changes = append(d.changes, Change{Kind: Modified, Path: "header", Attribute: "version", Old: oldVersion, New: newVersion})
+	}
+
+	for _, regionName := range unionNames(a.RegionNames(), b.RegionNames()) {
+		oldRegion, inOld := a.Regions[regionName]
+		newRegion, inNew := b.Regions[regionName]
+		switch {
+		case !inNew:
+			d.changes = append(d.changes, Change{Kind: Removed, Path: regionName})
+		case !inOld:
+			d.changes = append(d.changes, Change{Kind: Added, Path: regionName})
+		default:
+			d.diffNode(regionName, &oldRegion.Node, &newRegion.Node)
+		}
+	}
+
+	return d.changes
+}
+
+type differ struct {
+	sorter  *resource.Sorter
+	changes []Change
+}
+
+func (d *differ) diffNode(path string, a, b *resource.Node) {
+	for _, attrName := range unionNames(a.AttributeNames(), b.AttributeNames()) {
+		oldAttr, inOld := a.Attributes[attrName]
+		newAttr, inNew := b.Attributes[attrName]
+		switch {
+		case !inNew:
+			d.changes = append(d.changes, Change{Kind: Removed, Path: path, Attribute: attrName, Type: oldAttr.Type.String(), Old: AttributeText(oldAttr)})
+		case !inOld:
+			d.changes = append(d.changes, Change{Kind: Added, Path: path, Attribute: attrName, Type: newAttr.Type.String(), New: AttributeText(newAttr)})
+		default:
+			oldText, newText := AttributeText(oldAttr), AttributeText(newAttr)
+			if oldAttr.Type == newAttr.Type && oldText == newText {
+				continue
+			}
+			change := Change{Kind: Modified, Path: path, Attribute: attrName, Type: newAttr.Type.String(), Old: oldText, New: newText}
+			if oldAttr.Type != newAttr.Type {
+				change.OldType = oldAttr.Type.String()
+			}
+			d.changes = append(d.changes, change)
+		}
+	}
+
+	for _, childName := range unionNames(a.ChildNames(), b.ChildNames()) {
+		d.diffSiblings(path, a.Children[childName], b.Children[childName])
+	}
+}
+
+func (d *differ) diffSiblings(parentPath string, a, b []*resource.Node) {
+	a = d.sorter.Sort(a)
+	b = d.sorter.Sort(b)
+	matches := Match(d.sorter, a, b)
+
+	for i, node := range a {
+		j := matches[i]
+		if j == -1 {
+			d.changes = append(d.changes, Change{Kind: Removed, Path: NodePath(d.sorter, parentPath, node, i, len(a))})
+			continue
+		}
+		if d.sorter.Digest(node) != d.sorter.Digest(b[j]) {
+			d.diffNode(NodePath(d.sorter, parentPath, b[j], j, len(b)), node, b[j])
+		}
+	}
+
+	matched := make([]bool, len(b))
+	for _, j := range matches {
+		if j != -1 {
+			matched[j] = true
+		}
+	}
+	for j, node := range b {
+		if !matched[j] {
+			d.changes = append(d.changes, Change{Kind: Added, Path: NodePath(d.sorter, parentPath, node, j, len(b))})
+		}
+	}
+}
+
+// NodePath names a node by its identity when it has one, or its position among its sorted same-named siblings
+func NodePath(sorter *resource.Sorter, parentPath string, node *resource.Node, index int, siblings int) string {
+	path := parentPath + "/" + node.Name
+	if attrName, value, ok := sorter.Identity(node); ok {
+		return fmt.Sprintf("%s[%s=%s]", path, attrName, value)
+	}
+	if siblings > 1 {
+		return fmt.Sprintf("%s[%d]", path, index)
+	}
+	return path
+}
+
+/*
+Match pairs up same-named siblings from two versions of a node, see Resources for the passes.
+Both slices should already be sorted with sorter. The result holds the index in b matched to each node
+of a, or -1 for nodes that have no counterpart.
+*/
+func Match(sorter *resource.Sorter, a, b []*resource.Node) []int {
+	return match(sorter, a, b, true)
+}
+
+// MatchStrict is Match without the positional pass, so only nodes with the same identity or contents are paired
+func MatchStrict(sorter *resource.Sorter, a, b []*resource.Node) []int {
+	return match(sorter, a, b, false)
+}
+
+func match(sorter *resource.Sorter, a, b []*resource.Node, byPosition bool) []int {
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	matched := make([]bool, len(b))
+
+	// "" for nodes without an identity
+	identity := func(node *resource.Node) string {
+		if attrName, value, ok := sorter.Identity(node); ok {
+			return attrName + "=" + value
+		}
+		return ""
+	}
+	identitiesA := make([]string, len(a))
+	for i, node := range a {
+		identitiesA[i] = identity(node)
+	}
+	identitiesB := make([]string, len(b))
+	candidates := make(map[string][]int)
+	for j, node := range b {
+		identitiesB[j] = identity(node)
+		if identitiesB[j] != "" {
+			candidates[identitiesB[j]] = append(candidates[identitiesB[j]], j)
+		}
+	}
+
+	// Pass 1: identity
+	for i := range a {
+		if identitiesA[i] == "" {
+			continue
+		}
+		if js := candidates[identitiesA[i]]; len(js) > 0 {
+			matches[i] = js[0]
+			matched[js[0]] = true
+			candidates[identitiesA[i]] = js[1:]
+		}
+	}
+
+	// Pass 2: unchanged nodes without an identity
+	hashes := make(map[resource.Digest][]int)
+	for j, node := range b {
+		if identitiesB[j] == "" {
+			hash := sorter.Digest(node)
+			hashes[hash] = append(hashes[hash], j)
+		}
+	}
+	for i, node := range a {
+		if identitiesA[i] != "" {
+			continue
+		}
+		hash := sorter.Digest(node)
+		if js := hashes[hash]; len(js) > 0 {
+			matches[i] = js[0]
+			matched[js[0]] = true
+			hashes[hash] = js[1:]
+		}
+	}
+
+	if !byPosition {
+		return matches
+	}
+
+	// Pass 3: the rest without an identity, in order
+	j := 0
+	for i := range a {
+		if matches[i] != -1 || identitiesA[i] != "" {
+			continue
+		}
+		for j < len(b) && (matched[j] || identitiesB[j] != "") {
+			j++
+		}
+		if j == len(b) {
+			break
+		}
+		matches[i] = j
+		matched[j] = true
+	}
+
+	return matches
+}
+
+/*
+AttributeText formats an attribute value for display and comparison. This is the LSX value, apart from
+translated strings which also show their version and arguments (LSX splits those over several XML attributes).
+*/
+func AttributeText(attr *resource.NodeAttribute) string {
+	// Lazy attributes that fail to decode come out empty, like in ValueString
+	attr.Load()
+	switch value := attr.Value.(type) {
+	case *resource.TranslatedString:
+		if value.Value != "" {
+			return fmt.Sprintf("%s;%d %q", value.Handle, value.Version, value.Value)
+		}
+		return fmt.Sprintf("%s;%d", value.Handle, value.Version)
+	case *resource.TranslatedFSString:
+		return translatedFSStringText(value)
+	}
+	return attr.ValueString()
+}
+
+func translatedFSStringText(fs *resource.TranslatedFSString) string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s;%d", fs.Handle, fs.Version)
+	if fs.Value != "" {
+		fmt.Fprintf(&result, " %q", fs.Value)
+	}
+	if len(fs.Arguments) > 0 {
+		result.WriteString(" (")
+		for i, arg := range fs.Arguments {
+			if i > 0 {
+				result.WriteString(", ")
+			}
+			fmt.Fprintf(&result, "%s=%q %s", arg.Key, arg.Value, translatedFSStringText(&arg.String))
+		}
+		result.WriteString(")")
+	}
+	return result.String()
+}
+
+func formatVersion(metadata resource.LSMetadata) string {
+	return fmt.Sprintf("%d.%d.%d.%d", metadata.MajorVersion, metadata.MinorVersion, metadata.Revision, metadata.BuildNumber)
+}
+
+// Merges two sorted name lists
+func unionNames(a, b []string) []string {
+	names := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			names = append(names, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			names = append(names, b[j])
+			j++
+		default:
+			names = append(names, a[i])
+			i++
+			j++
+		}
+	}
+	return names
+}
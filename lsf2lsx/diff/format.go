@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// String formats the change as one line: "+ path" for added nodes, "- path" for removed ones, and
+// "~ path Attribute (type): "old" -> "new"" for attribute changes (added/removed attributes show one value)
+func (c Change) String() string {
+	prefix := map[Kind]string{Added: "+", Removed: "-", Modified: "~"}[c.Kind]
+	if c.Attribute == "" {
+		return fmt.Sprintf("%s %s", prefix, c.Path)
+	}
+
+	attribute := c.Attribute
+	if c.OldType != "" {
+		attribute += fmt.Sprintf(" (%s -> %s)", c.OldType, c.Type)
+	} else if c.Type != "" {
+		attribute += fmt.Sprintf(" (%s)", c.Type)
+	}
+
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s %s: %q", prefix, c.Path, attribute, c.New)
+	case Removed:
+		return fmt.Sprintf("%s %s %s: %q", prefix, c.Path, attribute, c.Old)
+	}
+	return fmt.Sprintf("%s %s %s: %q -> %q", prefix, c.Path, attribute, c.Old, c.New)
+}
+
+// WriteText writes the changes one per line
+func WriteText(w io.Writer, changes []Change) error {
+	for _, change := range changes {
+		_, err := fmt.Fprintln(w, change.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the changes as {"changes": [...]}
+func WriteJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(struct {
+		Changes []Change `json:"changes"`
+	}{changes})
+}
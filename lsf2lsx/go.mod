@@ -1,4 +1,4 @@
-module lsf2lsx
+module github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx
 
 go 1.21
 
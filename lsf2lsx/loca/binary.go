@@ -0,0 +1,140 @@
+package loca
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// LOCA file signature
+var Signature = []byte{'L', 'O', 'C', 'A'}
+
+// Keys are stored in a fixed size field, null terminated
+const maxKeyLength = 63
+
+// Header is at the start of a binary .loca file, after the signature
+type Header struct {
+	NumEntries  uint32
+	TextsOffset uint32
+}
+
+// EntryHeader describes an entry, the texts follow all the entry headers in the same order
+type EntryHeader struct {
+	Key     [64]byte
+	Version uint16
+	// Length of the text including its null terminator
+	Length uint32
+}
+
+/*
+ReadBinary reads the binary format:
+
+	"LOCA" | Header | EntryHeader * NumEntries | texts (at TextsOffset)
+*/
+func ReadBinary(r io.Reader) (*Resource, error) {
+	signature := make([]byte, len(Signature))
+	_, err := io.ReadFull(r, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	if !bytes.Equal(signature, Signature) {
+		return nil, fmt.Errorf("not a .loca file")
+	}
+
+	var header Header
+	err = binary.Read(r, binary.LittleEndian, &header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// Read the entry headers one by one, so a bogus count fails at the end of the file rather than allocating
+	entryHeaders := make([]EntryHeader, 0)
+	for i := uint32(0); i < header.NumEntries; i++ {
+		var entryHeader EntryHeader
+		err = binary.Read(r, binary.LittleEndian, &entryHeader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+		entryHeaders = append(entryHeaders, entryHeader)
+	}
+
+	headersEnd := uint64(len(Signature)+binary.Size(header)) + uint64(header.NumEntries)*uint64(binary.Size(EntryHeader{}))
+	if uint64(header.TextsOffset) < headersEnd {
+		return nil, fmt.Errorf("texts offset %d overlaps the entries (which end at %d)", header.TextsOffset, headersEnd)
+	}
+	_, err = io.CopyN(io.Discard, r, int64(uint64(header.TextsOffset)-headersEnd))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek to texts: %w", err)
+	}
+
+	res := &Resource{Entries: make([]Entry, 0, len(entryHeaders))}
+	for _, entryHeader := range entryHeaders {
+		key := nullTerminated(entryHeader.Key[:])
+		text := &bytes.Buffer{}
+		_, err = io.CopyN(text, r, int64(entryHeader.Length))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read text of %s: %w", key, err)
+		}
+
+		res.Entries = append(res.Entries, Entry{
+			Key:     key,
+			Version: entryHeader.Version,
+			Text:    nullTerminated(text.Bytes()),
+		})
+	}
+
+	return res, nil
+}
+
+// WriteBinary writes the binary format
+func WriteBinary(w io.Writer, res *Resource, opts *WriterOptions) error {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+	entries := res.orderedEntries(opts.Order)
+
+	header := Header{
+		NumEntries:  uint32(len(entries)),
+		TextsOffset: uint32(len(Signature)+binary.Size(Header{})) + uint32(len(entries)*binary.Size(EntryHeader{})),
+	}
+	_, err := w.Write(Signature)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, binary.LittleEndian, &header)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if len(entry.Key) > maxKeyLength {
+			return fmt.Errorf("key %q is longer than %d bytes", entry.Key, maxKeyLength)
+		}
+		entryHeader := EntryHeader{
+			Version: entry.Version,
+			Length:  uint32(len(entry.Text) + 1),
+		}
+		copy(entryHeader.Key[:], entry.Key)
+		err = binary.Write(w, binary.LittleEndian, &entryHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		_, err = io.WriteString(w, entry.Text+"\x00")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nullTerminated(data []byte) string {
+	if end := bytes.IndexByte(data, 0); end != -1 {
+		data = data[:end]
+	}
+	return string(data)
+}
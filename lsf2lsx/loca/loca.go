@@ -0,0 +1,82 @@
+// Package loca reads and writes BG3's localization files: the binary .loca format and its XML contentList form.
+package loca
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Entry is one translated text, looked up by the handle of a TranslatedString
+type Entry struct {
+	Key     string
+	Version uint16
+	Text    string
+}
+
+// Resource holds the entries of a localization file, in file order
+type Resource struct {
+	Entries []Entry
+}
+
+// Texts returns the texts by handle. Later entries win if a handle is listed twice, like in the game.
+func (r *Resource) Texts() map[string]string {
+	texts := make(map[string]string, len(r.Entries))
+	for _, entry := range r.Entries {
+		texts[entry.Key] = entry.Text
+	}
+	return texts
+}
+
+// Entries in the given order, sorted means by key (then version)
+func (r *Resource) orderedEntries(order resource.Order) []Entry {
+	if order == resource.OrderFile {
+		return r.Entries
+	}
+	sorted := make([]Entry, len(r.Entries))
+	copy(sorted, r.Entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+// WriterOptions configures the output of both formats. The zero value (and nil) sorts entries by key.
+type WriterOptions struct {
+	// Sorted, or the order the entries were read in
+	Order resource.Order
+}
+
+// Wrapper for Read to handle file opening, also takes "archive.pak:path/in/archive" paths
+func ReadFile(filename string) (*Resource, error) {
+	file, err := pak.OpenPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read reads either format, telling them apart by the LOCA signature
+func Read(r io.Reader) (*Resource, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if IsBinary(data) {
+		return ReadBinary(bytes.NewReader(data))
+	}
+	return ReadXML(bytes.NewReader(data))
+}
+
+// IsBinary reports whether data starts with the binary .loca signature
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, Signature)
+}
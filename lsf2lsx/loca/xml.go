@@ -0,0 +1,76 @@
+package loca
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type xmlContentList struct {
+	XMLName  xml.Name     `xml:"contentList"`
+	Contents []xmlContent `xml:"content"`
+}
+
+type xmlContent struct {
+	UID     string `xml:"contentuid,attr"`
+	Version string `xml:"version,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ReadXML reads the XML form. Entries without a version get version 1, like Divine does.
+func ReadXML(r io.Reader) (*Resource, error) {
+	var contentList xmlContentList
+	err := xml.NewDecoder(r).Decode(&contentList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contentList document: %w", err)
+	}
+
+	res := &Resource{Entries: make([]Entry, 0, len(contentList.Contents))}
+	for _, content := range contentList.Contents {
+		entry := Entry{Key: content.UID, Version: 1, Text: content.Text}
+		if content.Version != "" {
+			version, err := strconv.ParseUint(content.Version, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("content %s: invalid version %q", content.UID, content.Version)
+			}
+			entry.Version = uint16(version)
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+
+	return res, nil
+}
+
+/*
+WriteXML writes the XML form:
+
+	<contentList>
+		<content contentuid="h0123..." version="1">Text</content>
+	</contentList>
+
+encoding/xml would escape line breaks as &#xA;, so the text is escaped by hand to keep multi-line texts
+readable in diffs.
+*/
+func WriteXML(w io.Writer, res *Resource, opts *WriterOptions) error {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	out.WriteString("<contentList>\n")
+	for _, entry := range res.orderedEntries(opts.Order) {
+		fmt.Fprintf(&out, "\t<content contentuid=\"%s\" version=\"%d\">%s</content>\n",
+			attributeEscaper.Replace(entry.Key), entry.Version, textEscaper.Replace(entry.Text))
+	}
+	out.WriteString("</contentList>\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+var attributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
@@ -0,0 +1,152 @@
+package lsf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Made up GUIDs in the usual text form, numbered so they're all different
+func benchGUID(i int) string {
+	return fmt.Sprintf("%08x-%04x-4000-8000-%012x", i*2654435761%(1<<32), i%0xffff, i)
+}
+
+func benchUUID(i int) []byte {
+	uuid := make([]byte, 16)
+	for j := range uuid {
+		uuid[j] = byte(i >> (j % 4 * 8))
+	}
+	return uuid
+}
+
+func benchResource(regionName string) (*resource.Resource, *resource.Region) {
+	res := &resource.Resource{Metadata: resource.LSMetadata{MajorVersion: 4, MinorVersion: 7, Revision: 1, BuildNumber: 3}}
+	region := &resource.Region{RegionName: regionName, Node: resource.Node{Name: regionName}}
+	res.AddRegion(region)
+	return res, region
+}
+
+func benchChild(parent *resource.Node, name string) *resource.Node {
+	child := &resource.Node{Name: name, Parent: parent}
+	parent.AppendChild(child)
+	return child
+}
+
+func setAttr(node *resource.Node, name string, attrType resource.AttributeType, value interface{}) {
+	node.SetAttribute(name, &resource.NodeAttribute{Type: attrType, Value: value})
+}
+
+// Shaped like a _merged.lsf of root templates: lots of strings, GUIDs and translated strings, a few tags each
+func rootTemplatesResource(count int) *resource.Resource {
+	res, region := benchResource("Templates")
+	for i := 0; i < count; i++ {
+		object := benchChild(&region.Node, "GameObjects")
+		object.KeyAttribute = "MapKey"
+		setAttr(object, "MapKey", resource.AttrFixedString, benchGUID(i))
+		setAttr(object, "ParentTemplateId", resource.AttrFixedString, benchGUID(i/10))
+		setAttr(object, "Name", resource.AttrLSString, fmt.Sprintf("TMP_Object_%d", i))
+		setAttr(object, "Type", resource.AttrFixedString, "item")
+		setAttr(object, "VisualTemplate", resource.AttrFixedString, benchGUID(i+count))
+		setAttr(object, "PhysicsTemplate", resource.AttrFixedString, benchGUID(i+2*count))
+		setAttr(object, "Stats", resource.AttrFixedString, fmt.Sprintf("OBJ_Stats_%d", i%500))
+		setAttr(object, "DisplayName", resource.AttrTranslatedString, &resource.TranslatedString{Version: 1, Handle: fmt.Sprintf("h%08xg%04xg4000g8000g%012x", i, i%0xffff, i)})
+		setAttr(object, "Description", resource.AttrTranslatedString, &resource.TranslatedString{Version: 2, Handle: fmt.Sprintf("h%08xg%04xg4000g8000g%012x", i+count, i%0xffff, i)})
+		setAttr(object, "TechnicalDescription", resource.AttrTranslatedFSString, &resource.TranslatedFSString{
+			Version: 1,
+			Handle:  fmt.Sprintf("h%08xg0000g4000g8000g%012x", i, i),
+			Arguments: []resource.TranslatedFSStringArgument{
+				{Key: "Damage", Value: "1d8", String: resource.TranslatedFSString{Handle: "ls::TranslatedStringRepository::s_HandleUnknown"}},
+			},
+		})
+		setAttr(object, "Flag", resource.AttrUInt, uint32(i%7))
+		setAttr(object, "LevelOverride", resource.AttrInt, int32(-1))
+		setAttr(object, "Scale", resource.AttrFloat, float32(1))
+		setAttr(object, "CastShadow", resource.AttrBool, i%2 == 0)
+		setAttr(object, "ReceiveDecal", resource.AttrBool, false)
+		setAttr(object, "CoverAmount", resource.AttrByte, uint8(i%100))
+		setAttr(object, "AnubisConfigName", resource.AttrFixedString, "")
+
+		tags := benchChild(object, "Tags")
+		for j := 0; j < 3; j++ {
+			tag := benchChild(tags, "Tag")
+			setAttr(tag, "Object", resource.AttrUUID, benchUUID(i*3+j))
+		}
+	}
+	return res
+}
+
+// Shaped like a level's items or characters: transforms, GUIDs and numbers rather than text
+func levelResource(count int) *resource.Resource {
+	res, region := benchResource("Templates")
+	for i := 0; i < count; i++ {
+		object := benchChild(&region.Node, "GameObjects")
+		object.KeyAttribute = "MapKey"
+		setAttr(object, "MapKey", resource.AttrFixedString, benchGUID(i))
+		setAttr(object, "TemplateName", resource.AttrFixedString, benchGUID(i%2000))
+		setAttr(object, "LevelName", resource.AttrFixedString, "WLD_Main_A")
+		setAttr(object, "Type", resource.AttrFixedString, "item")
+		setAttr(object, "Flags", resource.AttrUInt, uint32(i))
+		setAttr(object, "Amount", resource.AttrInt, int32(1))
+		setAttr(object, "IsGlobal", resource.AttrBool, false)
+		setAttr(object, "GroupID", resource.AttrUInt, uint32(i%64))
+		setAttr(object, "_OriginalFileVersion_", resource.AttrInt64, int64(144115207403209032))
+
+		transform := benchChild(object, "Transform")
+		setAttr(transform, "Position", resource.AttrVec3, [3]float32{float32(i), float32(i % 100), float32(-i)})
+		setAttr(transform, "RotationQuat", resource.AttrVec4, [4]float32{0, 0.7071, 0, 0.7071})
+		setAttr(transform, "Scale", resource.AttrFloat, float32(1))
+
+		bounds := benchChild(object, "Bounds")
+		setAttr(bounds, "Min", resource.AttrVec3, [3]float32{-1, -1, -1})
+		setAttr(bounds, "Max", resource.AttrVec3, [3]float32{1, 1, 1})
+		setAttr(bounds, "Matrix", resource.AttrMat4, []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1})
+	}
+	return res
+}
+
+func benchmarkRead(b *testing.B, res *resource.Resource, opts *ReaderOptions) {
+	for _, method := range []compression.Method{compression.None, compression.LZ4} {
+		var data bytes.Buffer
+		writerOpts := DefaultWriterOptions
+		writerOpts.Compression = compression.MakeFlags(method, compression.LevelDefault)
+		err := Write(&data, res, &writerOpts)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(method.String(), func(b *testing.B) {
+			b.SetBytes(int64(data.Len()))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := NewReaderWithOptions(bytes.NewReader(data.Bytes()), opts).Read()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReadRootTemplates(b *testing.B) {
+	benchmarkRead(b, rootTemplatesResource(5000), nil)
+}
+
+// Looking up one object, without decoding the values of the others
+func BenchmarkReadRootTemplatesPath(b *testing.B) {
+	opts := DefaultReaderOptions
+	opts.Paths = []string{"Templates/GameObjects[MapKey=" + benchGUID(2500) + "]"}
+	benchmarkRead(b, rootTemplatesResource(5000), &opts)
+}
+
+func BenchmarkReadRootTemplatesLazy(b *testing.B) {
+	opts := DefaultReaderOptions
+	opts.LazyValues = true
+	benchmarkRead(b, rootTemplatesResource(5000), &opts)
+}
+
+func BenchmarkReadLevel(b *testing.B) {
+	benchmarkRead(b, levelResource(20000), nil)
+}
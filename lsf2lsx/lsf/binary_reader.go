@@ -0,0 +1,310 @@
+// Unified handler for binary reading operations
+
+package lsf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+/*
+binaryReader decodes little-endian values straight out of a decompressed section. Values are sliced out of
+the buffer and put together with encoding/binary's byte order functions, so nothing goes through reflection
+and fixed size values don't allocate.
+
+Like io.ReadFull, reads fail with io.EOF when the section has nothing left and io.ErrUnexpectedEOF when it
+ends partway through a value.
+*/
+type binaryReader struct {
+	data   []byte
+	offset int
+}
+
+func newBinaryReader(data []byte) *binaryReader {
+	return &binaryReader{data: data}
+}
+
+// Reads the file front to back, counting the offset so errors can point at it without seeking
+type fileReader struct {
+	reader io.Reader
+	offset int64
+	// Size of the file, -1 when it can't be known without reading it all
+	size int64
+}
+
+func newFileReader(r io.Reader) (*fileReader, error) {
+	reader := &fileReader{reader: r, size: -1}
+	if seeker, ok := r.(io.Seeker); ok {
+		// Offsets are from wherever the LSF starts in the stream. Pipes are *os.Files too, but can't seek.
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return reader, nil
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		_, err = seeker.Seek(start, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		reader.size = end - start
+	}
+	return reader, nil
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes left
+func (r *binaryReader) Len() int {
+	return len(r.data) - r.offset
+}
+
+// Offset returns the current position, for error messages
+func (r *binaryReader) Offset() int64 {
+	return int64(r.offset)
+}
+
+// Seek moves to an offset, which the caller has checked is in the section
+func (r *binaryReader) Seek(offset int) {
+	r.offset = offset
+}
+
+// next returns the next n bytes of the section, without copying them
+func (r *binaryReader) next(n int) ([]byte, error) {
+	if n > r.Len() {
+		if r.Len() == 0 {
+			return nil, io.EOF
+		}
+		r.offset = len(r.data)
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return data, nil
+}
+
+func (r *binaryReader) readUint8() (uint8, error) {
+	data, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+func (r *binaryReader) readUint16() (uint16, error) {
+	data, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(data), nil
+}
+
+func (r *binaryReader) readUint32() (uint32, error) {
+	data, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
+func (r *binaryReader) readUint64() (uint64, error) {
+	data, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(data), nil
+}
+
+func (r *binaryReader) readInt8() (int8, error) {
+	val, err := r.readUint8()
+	return int8(val), err
+}
+
+func (r *binaryReader) readInt16() (int16, error) {
+	val, err := r.readUint16()
+	return int16(val), err
+}
+
+func (r *binaryReader) readInt32() (int32, error) {
+	val, err := r.readUint32()
+	return int32(val), err
+}
+
+func (r *binaryReader) readInt64() (int64, error) {
+	val, err := r.readUint64()
+	return int64(val), err
+}
+
+func (r *binaryReader) readFloat32() (float32, error) {
+	val, err := r.readUint32()
+	return math.Float32frombits(val), err
+}
+
+func (r *binaryReader) readFloat64() (float64, error) {
+	val, err := r.readUint64()
+	return math.Float64frombits(val), err
+}
+
+// Fills vals, for vectors and matrices
+func (r *binaryReader) readInt32s(vals []int32) error {
+	data, err := r.next(4 * len(vals))
+	if err != nil {
+		return err
+	}
+	for i := range vals {
+		vals[i] = int32(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return nil
+}
+
+func (r *binaryReader) readFloat32s(vals []float32) error {
+	data, err := r.next(4 * len(vals))
+	if err != nil {
+		return err
+	}
+	for i := range vals {
+		vals[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return nil
+}
+
+// Copies the next n bytes, for values that keep them
+func (r *binaryReader) readBytes(n int) ([]byte, error) {
+	data, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Sizes of the fixed size entries in the nodes, attributes and keys sections
+var (
+	nodeEntrySize      = binary.Size(NodeEntryV3{})
+	attributeEntrySize = binary.Size(AttributeEntryV3{})
+	keyEntrySize       = binary.Size(KeyEntry{})
+)
+
+func (r *binaryReader) readNodeEntry() (NodeEntryV3, error) {
+	data, err := r.next(nodeEntrySize)
+	if err != nil {
+		return NodeEntryV3{}, err
+	}
+	return NodeEntryV3{
+		NameHashTableIndex:  binary.LittleEndian.Uint32(data[0:]),
+		ParentIndex:         int32(binary.LittleEndian.Uint32(data[4:])),
+		NextSiblingIndex:    int32(binary.LittleEndian.Uint32(data[8:])),
+		FirstAttributeIndex: int32(binary.LittleEndian.Uint32(data[12:])),
+	}, nil
+}
+
+func (r *binaryReader) readAttributeEntry() (AttributeEntryV3, error) {
+	data, err := r.next(attributeEntrySize)
+	if err != nil {
+		return AttributeEntryV3{}, err
+	}
+	return AttributeEntryV3{
+		NameHashTableIndex: binary.LittleEndian.Uint32(data[0:]),
+		TypeAndLength:      binary.LittleEndian.Uint32(data[4:]),
+		NextAttributeIndex: int32(binary.LittleEndian.Uint32(data[8:])),
+		Offset:             binary.LittleEndian.Uint32(data[12:]),
+	}, nil
+}
+
+func (r *binaryReader) readKeyEntry() (KeyEntry, error) {
+	data, err := r.next(keyEntrySize)
+	if err != nil {
+		return KeyEntry{}, err
+	}
+	return KeyEntry{
+		NodeIndex: binary.LittleEndian.Uint32(data[0:]),
+		KeyName:   binary.LittleEndian.Uint32(data[4:]),
+	}, nil
+}
+
+// reads a value based on attribute type
+func readAttributeValue(attrType resource.AttributeType, reader *binaryReader) (interface{}, error) {
+	switch attrType {
+	case resource.AttrNone:
+		return nil, nil
+	case resource.AttrByte:
+		return reader.readUint8()
+	case resource.AttrShort:
+		return reader.readInt16()
+	case resource.AttrUShort:
+		return reader.readUint16()
+	case resource.AttrInt:
+		return reader.readInt32()
+	case resource.AttrUInt:
+		return reader.readUint32()
+	case resource.AttrFloat:
+		return reader.readFloat32()
+	case resource.AttrDouble:
+		return reader.readFloat64()
+	case resource.AttrBool:
+		val, err := reader.readUint8()
+		return val != 0, err
+	case resource.AttrULongLong:
+		return reader.readUint64()
+	case resource.AttrLong, resource.AttrInt64:
+		return reader.readInt64()
+	case resource.AttrInt8:
+		return reader.readInt8()
+	case resource.AttrIVec2:
+		var val [2]int32
+		err := reader.readInt32s(val[:])
+		return val, err
+	case resource.AttrIVec3:
+		var val [3]int32
+		err := reader.readInt32s(val[:])
+		return val, err
+	case resource.AttrIVec4:
+		var val [4]int32
+		err := reader.readInt32s(val[:])
+		return val, err
+	case resource.AttrVec2:
+		var val [2]float32
+		err := reader.readFloat32s(val[:])
+		return val, err
+	case resource.AttrVec3:
+		var val [3]float32
+		err := reader.readFloat32s(val[:])
+		return val, err
+	case resource.AttrVec4:
+		var val [4]float32
+		err := reader.readFloat32s(val[:])
+		return val, err
+	case resource.AttrMat2:
+		return readFloats(reader, 2*2)
+	case resource.AttrMat3:
+		return readFloats(reader, 3*3)
+	case resource.AttrMat3x4:
+		return readFloats(reader, 3*4)
+	case resource.AttrMat4x3:
+		return readFloats(reader, 4*3)
+	case resource.AttrMat4:
+		return readFloats(reader, 4*4)
+	case resource.AttrUUID:
+		// UUID is 16 bytes
+		return reader.readBytes(16)
+	default:
+		return nil, fmt.Errorf("unknown attribute type %d", attrType)
+	}
+}
+
+func readFloats(reader *binaryReader, count int) ([]float32, error) {
+	vals := make([]float32, count)
+	err := reader.readFloat32s(vals)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
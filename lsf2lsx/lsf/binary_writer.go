@@ -0,0 +1,102 @@
+// Unified handler for binary writing operations
+
+package lsf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+func writeUint16(writer io.Writer, val uint16) error {
+	return binary.Write(writer, binary.LittleEndian, val)
+}
+
+func writeUint32(writer io.Writer, val uint32) error {
+	return binary.Write(writer, binary.LittleEndian, val)
+}
+
+func writeInt32(writer io.Writer, val int32) error {
+	return binary.Write(writer, binary.LittleEndian, val)
+}
+
+// writes a value based on attribute type (the reverse of readAttributeValue)
+func writeAttributeValue(attrType resource.AttributeType, value interface{}, writer io.Writer) error {
+	var ok bool
+	switch attrType {
+	case resource.AttrNone:
+		// No data, like the reader expects
+		if value != nil {
+			return fmt.Errorf("invalid value of type %T for %s attribute", value, attrType)
+		}
+		return nil
+	case resource.AttrByte:
+		_, ok = value.(uint8)
+	case resource.AttrShort:
+		_, ok = value.(int16)
+	case resource.AttrUShort:
+		_, ok = value.(uint16)
+	case resource.AttrInt:
+		_, ok = value.(int32)
+	case resource.AttrUInt:
+		_, ok = value.(uint32)
+	case resource.AttrFloat:
+		_, ok = value.(float32)
+	case resource.AttrDouble:
+		_, ok = value.(float64)
+	case resource.AttrBool:
+		var val bool
+		if val, ok = value.(bool); ok {
+			value = uint8(0)
+			if val {
+				value = uint8(1)
+			}
+		}
+	case resource.AttrULongLong:
+		_, ok = value.(uint64)
+	case resource.AttrLong, resource.AttrInt64:
+		_, ok = value.(int64)
+	case resource.AttrInt8:
+		_, ok = value.(int8)
+	case resource.AttrIVec2:
+		_, ok = value.([2]int32)
+	case resource.AttrIVec3:
+		_, ok = value.([3]int32)
+	case resource.AttrIVec4:
+		_, ok = value.([4]int32)
+	case resource.AttrVec2:
+		_, ok = value.([2]float32)
+	case resource.AttrVec3:
+		_, ok = value.([3]float32)
+	case resource.AttrVec4:
+		_, ok = value.([4]float32)
+	case resource.AttrMat2:
+		ok = isFloatSlice(value, 2*2)
+	case resource.AttrMat3:
+		ok = isFloatSlice(value, 3*3)
+	case resource.AttrMat3x4:
+		ok = isFloatSlice(value, 3*4)
+	case resource.AttrMat4x3:
+		ok = isFloatSlice(value, 4*3)
+	case resource.AttrMat4:
+		ok = isFloatSlice(value, 4*4)
+	case resource.AttrUUID:
+		// UUID is 16 bytes
+		uuid, isBytes := value.([]byte)
+		ok = isBytes && len(uuid) == 16
+	default:
+		return fmt.Errorf("cannot write attribute type %s as a plain value", attrType)
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid value of type %T for %s attribute", value, attrType)
+	}
+	return binary.Write(writer, binary.LittleEndian, value)
+}
+
+func isFloatSlice(value interface{}, length int) bool {
+	vals, ok := value.([]float32)
+	return ok && len(vals) == length
+}
@@ -0,0 +1,149 @@
+package lsf
+
+import (
+	"io"
+	"sync"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+)
+
+// A section as it's stored in the file, decompressed once all of them have been read
+type rawSection struct {
+	name string
+	// Where the section starts in the file, for errors
+	offset           int64
+	data             []byte
+	uncompressedSize uint32
+	compressed       bool
+	allowChunked     bool
+}
+
+// readRawSection reads a section without decompressing it
+func (r *Reader) readRawSection(reader *fileReader, sizeOnDisk, uncompressedSize uint32) ([]byte, bool, error) {
+	if sizeOnDisk == 0 && uncompressedSize == 0 {
+		// No data
+		return []byte{}, false, nil
+	}
+
+	// Sections with no size on disk are stored uncompressed, whatever the flags say
+	isCompressed := sizeOnDisk != 0 && r.metadata.CompressionFlags.Method() != compression.None
+	size := uncompressedSize
+	if isCompressed {
+		size = sizeOnDisk
+	}
+
+	data, err := reader.readFull(int(size))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, isCompressed, nil
+}
+
+// Sections of streams without a known size are read this much at a time
+const sectionChunkSize = 1 << 20
+
+/*
+readFull reads the next size bytes. When the file size is unknown, the sizes in the file haven't been checked
+against it, so the buffer only grows as data arrives: a few bytes claiming a huge section run out of data long
+before they use up memory.
+*/
+func (r *fileReader) readFull(size int) ([]byte, error) {
+	if r.size >= 0 || size <= sectionChunkSize {
+		data := make([]byte, size)
+		_, err := io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	data := make([]byte, 0, sectionChunkSize)
+	for len(data) < size {
+		chunk := size - len(data)
+		if chunk > sectionChunkSize {
+			chunk = sectionChunkSize
+		}
+		if cap(data)-len(data) < chunk {
+			grown := make([]byte, len(data), 2*cap(data))
+			if cap(grown) > size {
+				grown = grown[:len(data):size]
+			}
+			copy(grown, data)
+			data = grown
+		}
+		start := len(data)
+		data = data[:start+chunk]
+		_, err := io.ReadFull(r, data[start:])
+		if err == io.EOF && start > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+/*
+decompressSections decompresses the sections in place, up to opts.Concurrency of them at the same time.
+Each section's error is wrapped with where it is, and the first one in file order is returned.
+*/
+func (r *Reader) decompressSections(sections []*rawSection) error {
+	errs := make([]error, len(sections))
+	decompress := func(i int) {
+		section := sections[i]
+		// BG3 always supports chunked compression (version >= 2)
+		data, err := compression.Decompress(section.data, int(section.uncompressedSize), r.metadata.CompressionFlags, section.allowChunked)
+		if err != nil {
+			errs[i] = newDecodeError(section.name, section.offset, err)
+			return
+		}
+		section.data = data
+		section.compressed = false
+	}
+
+	compressed := make([]int, 0, len(sections))
+	for i, section := range sections {
+		if section.compressed {
+			compressed = append(compressed, i)
+		}
+	}
+
+	if r.opts.Concurrency == 1 || len(compressed) < 2 {
+		for _, i := range compressed {
+			decompress(i)
+		}
+	} else {
+		limit := r.opts.Concurrency
+		if limit <= 0 || limit > len(compressed) {
+			limit = len(compressed)
+		}
+		slots := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+		for _, i := range compressed {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				decompress(i)
+				<-slots
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress compresses a section with the writer's compression flags
+func (w *Writer) compress(data []byte, allowChunked bool) ([]byte, error) {
+	if len(data) == 0 || w.compressionFlags.Method() == compression.None {
+		return data, nil
+	}
+	return compression.Compress(data, w.compressionFlags, allowChunked)
+}
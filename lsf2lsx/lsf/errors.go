@@ -0,0 +1,52 @@
+package lsf
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+DecodeError says where an LSF file failed to decode. Errors returned by Reader.Read are always DecodeErrors,
+so errors.As can get at the details.
+
+Offset is in bytes from the start of the decompressed section for problems with a section's contents, and
+from the start of the file for the header, metadata and reading or decompressing the sections themselves.
+It's -1 when the problem isn't at any one place.
+*/
+type DecodeError struct {
+	// header, metadata, names, nodes, attributes, values or keys, or options for ReaderOptions that are invalid
+	Section string
+	// Index of the node being decoded, -1 when the error isn't about a node
+	Node int
+	// Name of the attribute being decoded, if any
+	Attribute string
+	Offset    int64
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	var where strings.Builder
+	if e.Section == "options" {
+		where.WriteString("reader options")
+	} else {
+		fmt.Fprintf(&where, "%s section", e.Section)
+	}
+	if e.Node >= 0 {
+		fmt.Fprintf(&where, ", node %d", e.Node)
+	}
+	if e.Attribute != "" {
+		fmt.Fprintf(&where, ", attribute %q", e.Attribute)
+	}
+	if e.Offset >= 0 {
+		fmt.Fprintf(&where, ", offset %d (0x%x)", e.Offset, e.Offset)
+	}
+	return fmt.Sprintf("%s: %v", where.String(), e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func newDecodeError(section string, offset int64, err error) *DecodeError {
+	return &DecodeError{Section: section, Node: -1, Offset: offset, Err: err}
+}
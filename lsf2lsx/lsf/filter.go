@@ -0,0 +1,178 @@
+package lsf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// One node on a path: its name and the attribute values it must have
+type pathStep struct {
+	name       string
+	conditions []pathCondition
+}
+
+type pathCondition struct {
+	attribute string
+	value     string
+}
+
+/*
+parsePath splits a path like "Templates/GameObjects[MapKey=...]/Transform" into its steps, the form diff names
+nodes with a key or GUID in. The first step is the region. Values are compared to the attribute's LSX value,
+and can contain slashes but not a closing bracket. diff's positions like Child[2] count siblings in sorted
+order, which isn't known before the nodes are built, so they're rejected.
+*/
+func parsePath(path string) ([]pathStep, error) {
+	var steps []pathStep
+	rest := path
+	for {
+		end := strings.IndexAny(rest, "/[")
+		if end == -1 {
+			end = len(rest)
+		}
+		step := pathStep{name: rest[:end]}
+		if step.name == "" {
+			return nil, fmt.Errorf("path %q has an empty node name", path)
+		}
+		rest = rest[end:]
+
+		for strings.HasPrefix(rest, "[") {
+			end = strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("path %q has an unclosed [", path)
+			}
+			attribute, value, ok := strings.Cut(rest[1:end], "=")
+			if _, err := strconv.Atoi(attribute); !ok && err == nil {
+				return nil, fmt.Errorf("path %q: positions like %s aren't supported, use [Attribute=Value]", path, rest[:end+1])
+			}
+			if !ok || attribute == "" {
+				return nil, fmt.Errorf("path %q: expected [Attribute=Value], got %s", path, rest[:end+1])
+			}
+			step.conditions = append(step.conditions, pathCondition{attribute: attribute, value: value})
+			rest = rest[end+1:]
+		}
+		steps = append(steps, step)
+
+		if rest == "" {
+			return steps, nil
+		}
+		if rest[0] != '/' {
+			return nil, fmt.Errorf("path %q: expected / after ]", path)
+		}
+		rest = rest[1:]
+	}
+}
+
+// Parses the Paths option, failing with a DecodeError for the options like Read does for the file
+func parsePaths(paths []string) ([][]pathStep, error) {
+	parsed := make([][]pathStep, len(paths))
+	for p, path := range paths {
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, newDecodeError("options", -1, err)
+		}
+		parsed[p] = steps
+	}
+	return parsed, nil
+}
+
+/*
+selectNodes picks the nodes to build for the Regions and Paths options, or returns nil when every node is
+built. Nodes on a path are picked with everything below them, and the nodes above them so the tree stays
+connected.
+*/
+func (r *Reader) selectNodes() ([]bool, error) {
+	if len(r.opts.Regions) == 0 && len(r.paths) == 0 {
+		return nil, nil
+	}
+
+	regions := make(map[string]bool, len(r.opts.Regions))
+	for _, region := range r.opts.Regions {
+		regions[region] = true
+	}
+	paths := r.paths
+
+	selected := make([]bool, len(r.nodes))
+	inRegion := make([]bool, len(r.nodes))
+	depths := make([]int, len(r.nodes))
+	// For each path, whether each node matches as many of its steps as the node is deep
+	matches := make([][]bool, len(paths))
+	for p := range matches {
+		matches[p] = make([]bool, len(r.nodes))
+	}
+
+	for i := range r.nodes {
+		parent := r.nodes[i].ParentIndex
+		if parent == -1 {
+			inRegion[i] = len(regions) == 0 || regions[r.nodes[i].Name]
+		} else {
+			inRegion[i] = inRegion[parent]
+			depths[i] = depths[parent] + 1
+		}
+		if !inRegion[i] {
+			continue
+		}
+		if len(paths) == 0 || (parent != -1 && selected[parent]) {
+			selected[i] = true
+			continue
+		}
+
+		for p, steps := range paths {
+			depth := depths[i]
+			if depth >= len(steps) || (parent != -1 && !matches[p][parent]) {
+				continue
+			}
+			ok, err := r.matchStep(i, steps[depth])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matches[p][i] = true
+				if depth == len(steps)-1 {
+					selected[i] = true
+				}
+			}
+		}
+	}
+
+	// Parents come before their children, so going backwards reaches every ancestor
+	for i := len(r.nodes) - 1; i >= 0; i-- {
+		if selected[i] && r.nodes[i].ParentIndex != -1 {
+			selected[r.nodes[i].ParentIndex] = true
+		}
+	}
+	return selected, nil
+}
+
+// Checks a node's name and the attributes a path step asks for, decoding only those attributes
+func (r *Reader) matchStep(nodeIndex int, step pathStep) (bool, error) {
+	if r.nodes[nodeIndex].Name != step.name {
+		return false, nil
+	}
+	for _, condition := range step.conditions {
+		value, ok, err := r.attributeValue(nodeIndex, condition.attribute)
+		if err != nil || !ok || value != condition.value {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// The LSX value of one of a node's attributes, found by following its attribute chain
+func (r *Reader) attributeValue(nodeIndex int, name string) (string, bool, error) {
+	attrIdx := r.nodes[nodeIndex].FirstAttributeIndex
+	// Broken chains are reported when the node is built, here they just don't match
+	for steps := 0; attrIdx >= 0 && attrIdx < len(r.attributes) && steps < len(r.attributes); steps++ {
+		attrInfo := &r.attributes[attrIdx]
+		if attrInfo.Name == name {
+			attr, err := r.decodeAttribute(nodeIndex, attrInfo, newBinaryReader(r.values))
+			if err != nil {
+				return "", false, err
+			}
+			return attr.ValueString(), true, nil
+		}
+		attrIdx = attrInfo.NextAttributeIndex
+	}
+	return "", false, nil
+}
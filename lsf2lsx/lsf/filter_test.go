@@ -0,0 +1,212 @@
+package lsf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Two regions, with objects that have two kinds of children so paths can pick one of them
+func filterResource() *resource.Resource {
+	res, region := benchResource("Templates")
+	for i := 0; i < 3; i++ {
+		object := benchChild(&region.Node, "GameObjects")
+		object.KeyAttribute = "MapKey"
+		setAttr(object, "MapKey", resource.AttrFixedString, benchGUID(i))
+		setAttr(object, "Name", resource.AttrLSString, fmt.Sprintf("Object_%d", i))
+		transform := benchChild(object, "Transform")
+		setAttr(transform, "Position", resource.AttrVec3, [3]float32{float32(i), 0, 0})
+		tag := benchChild(object, "Tag")
+		setAttr(tag, "Object", resource.AttrUUID, benchUUID(i))
+	}
+	other := benchChild(&region.Node, "Other")
+	setAttr(other, "Path", resource.AttrPath, "Public/Mod/a/b")
+
+	config := &resource.Region{RegionName: "Config", Node: resource.Node{Name: "Config"}}
+	setAttr(&config.Node, "Version", resource.AttrInt, int32(3))
+	res.AddRegion(config)
+	return res
+}
+
+func writeLSF(t *testing.T, res *resource.Resource) []byte {
+	var data bytes.Buffer
+	err := Write(&data, res, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+// Every node as Region/Name/Name, with the MapKey of the nodes that have one
+func builtNodes(res *resource.Resource) []string {
+	var paths []string
+	var walk func(path string, node *resource.Node)
+	walk = func(path string, node *resource.Node) {
+		if attr, ok := node.Attributes["MapKey"]; ok {
+			path += "[MapKey=" + attr.ValueString() + "]"
+		}
+		paths = append(paths, path)
+		for _, childName := range node.ChildNames() {
+			for _, child := range node.Children[childName] {
+				walk(path+"/"+child.Name, child)
+			}
+		}
+	}
+	for _, regionName := range res.RegionNames() {
+		walk(regionName, &res.Regions[regionName].Node)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestReadFilters(t *testing.T) {
+	data := writeLSF(t, filterResource())
+	object1 := "Templates/GameObjects[MapKey=" + benchGUID(1) + "]"
+
+	tests := []struct {
+		name    string
+		regions []string
+		paths   []string
+		want    []string
+	}{
+		{
+			name:    "region",
+			regions: []string{"Config"},
+			want:    []string{"Config"},
+		},
+		{
+			name:  "key",
+			paths: []string{object1},
+			want:  []string{"Templates", object1, object1 + "/Tag", object1 + "/Transform"},
+		},
+		{
+			name:  "children of every object",
+			paths: []string{"Templates/GameObjects/Transform"},
+			want: []string{
+				"Templates",
+				"Templates/GameObjects[MapKey=" + benchGUID(0) + "]",
+				"Templates/GameObjects[MapKey=" + benchGUID(0) + "]/Transform",
+				object1,
+				object1 + "/Transform",
+				"Templates/GameObjects[MapKey=" + benchGUID(2) + "]",
+				"Templates/GameObjects[MapKey=" + benchGUID(2) + "]/Transform",
+			},
+		},
+		{
+			name:  "value with slashes",
+			paths: []string{"Templates/Other[Path=Public/Mod/a/b]", "Config"},
+			want:  []string{"Config", "Templates", "Templates/Other"},
+		},
+		{
+			name:    "path outside the regions",
+			regions: []string{"Config"},
+			paths:   []string{object1},
+			want:    nil,
+		},
+		{
+			name:  "no match",
+			paths: []string{"Templates/GameObjects[MapKey=missing]"},
+			want:  nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultReaderOptions
+			opts.Regions = test.regions
+			opts.Paths = test.paths
+			res, err := NewReaderWithOptions(bytes.NewReader(data), &opts).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(test.want)
+			if got := builtNodes(res); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("built %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadFiltersInvalidPath(t *testing.T) {
+	data := writeLSF(t, filterResource())
+	for _, path := range []string{"Templates//GameObjects", "Templates/GameObjects[MapKey", "Templates/GameObjects[1]", "Templates[x=1]y"} {
+		opts := DefaultReaderOptions
+		opts.Paths = []string{path}
+		_, err := NewReaderWithOptions(bytes.NewReader(data), &opts).Read()
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Section != "options" {
+			t.Errorf("%s: got %v, want an options DecodeError", path, err)
+		}
+	}
+}
+
+func TestReadLazyValues(t *testing.T) {
+	first := writeLSF(t, seedResource())
+	second := writeLSF(t, rootTemplatesResource(20))
+	eager, err := Read(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultReaderOptions
+	opts.LazyValues = true
+	reader := NewReaderWithOptions(bytes.NewReader(first), &opts)
+	lazy, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lazy.Regions["Templates"].Attributes["Count"].IsLoaded() {
+		t.Fatal("value was decoded while reading")
+	}
+
+	// Reading another file with the same Reader mustn't change what the first one's values decode to
+	reader.stream = bytes.NewReader(second)
+	_, err = reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writers load values as they need them
+	if written := writeLSF(t, lazy); !bytes.Equal(written, first) {
+		t.Error("writing lazy values gave a different file")
+	}
+
+	err = lazy.LoadValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareValues(t, eager, lazy)
+}
+
+func compareValues(t *testing.T, want, got *resource.Resource) {
+	var compare func(path string, want, got *resource.Node)
+	compare = func(path string, want, got *resource.Node) {
+		for name, wantAttr := range want.Attributes {
+			gotAttr := got.Attributes[name]
+			if gotAttr == nil || !gotAttr.IsLoaded() || !reflect.DeepEqual(gotAttr.Value, wantAttr.Value) {
+				t.Errorf("%s, attribute %s: got %#v, want %#v", path, name, gotAttr, wantAttr)
+			}
+		}
+		for name, wantChildren := range want.Children {
+			gotChildren := got.Children[name]
+			if len(gotChildren) != len(wantChildren) {
+				t.Errorf("%s: got %d %s children, want %d", path, len(gotChildren), name, len(wantChildren))
+				continue
+			}
+			for i := range wantChildren {
+				compare(path+"/"+name, wantChildren[i], gotChildren[i])
+			}
+		}
+	}
+	if !reflect.DeepEqual(want.RegionNames(), got.RegionNames()) {
+		t.Fatalf("got regions %s, want %s", strings.Join(got.RegionNames(), ", "), strings.Join(want.RegionNames(), ", "))
+	}
+	for _, regionName := range want.RegionNames() {
+		compare(regionName, &want.Regions[regionName].Node, &got.Regions[regionName].Node)
+	}
+}
@@ -0,0 +1,221 @@
+package lsf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Small limits keep the fuzzer from spending its time on allocations
+var fuzzReaderOptions = ReaderOptions{
+	MaxSectionSize: 1 << 20,
+	MaxTotalSize:   4 << 20,
+	MaxDepth:       32,
+}
+
+// Seed resource using every kind of attribute encoding: fixed size values, strings, translated strings with
+// nested arguments, buffers, keys and nested children
+func seedResource() *resource.Resource {
+	res := &resource.Resource{Metadata: resource.LSMetadata{MajorVersion: 4, MinorVersion: 1, Revision: 1, BuildNumber: 2}}
+
+	region := &resource.Region{RegionName: "Templates", Node: resource.Node{Name: "Templates"}}
+	region.SetAttribute("Count", &resource.NodeAttribute{Type: resource.AttrInt, Value: int32(-2)})
+	res.AddRegion(region)
+
+	child := &resource.Node{Name: "GameObjects", Parent: &region.Node, KeyAttribute: "MapKey"}
+	child.SetAttribute("MapKey", &resource.NodeAttribute{Type: resource.AttrFixedString, Value: "2a3b4c5d-0000-4000-8000-000000000001"})
+	child.SetAttribute("Name", &resource.NodeAttribute{Type: resource.AttrLSString, Value: "Sword"})
+	child.SetAttribute("UUID", &resource.NodeAttribute{Type: resource.AttrUUID, Value: make([]byte, 16)})
+	child.SetAttribute("Position", &resource.NodeAttribute{Type: resource.AttrVec3, Value: [3]float32{1, 2, 3}})
+	child.SetAttribute("Transform", &resource.NodeAttribute{Type: resource.AttrMat4, Value: make([]float32, 16)})
+	child.SetAttribute("Flag", &resource.NodeAttribute{Type: resource.AttrBool, Value: true})
+	child.SetAttribute("Big", &resource.NodeAttribute{Type: resource.AttrInt64, Value: int64(1) << 40})
+	child.SetAttribute("Buffer", &resource.NodeAttribute{Type: resource.AttrScratchBuffer, Value: []byte{1, 2, 3}})
+	child.SetAttribute("DisplayName", &resource.NodeAttribute{Type: resource.AttrTranslatedString, Value: &resource.TranslatedString{Version: 1, Handle: "h00000001"}})
+	child.SetAttribute("Description", &resource.NodeAttribute{Type: resource.AttrTranslatedFSString, Value: &resource.TranslatedFSString{
+		Version: 1,
+		Handle:  "h00000002",
+		Arguments: []resource.TranslatedFSStringArgument{
+			{Key: "Damage", Value: "1d8", String: resource.TranslatedFSString{Handle: "h00000003"}},
+		},
+	}})
+	region.AppendChild(child)
+
+	grandchild := &resource.Node{Name: "Tag", Parent: child}
+	grandchild.SetAttribute("Object", &resource.NodeAttribute{Type: resource.AttrUUID, Value: make([]byte, 16)})
+	child.AppendChild(grandchild)
+
+	return res
+}
+
+// Seed files in every version, uncompressed and with each compression method
+func seedFiles(f *testing.F) [][]byte {
+	methods := []compression.Method{compression.None, compression.Zlib, compression.LZ4, compression.Zstd}
+	files := make([][]byte, 0)
+	for version := uint32(VersionMin); version <= VersionMax; version++ {
+		for _, method := range methods {
+			var level uint8
+			if method != compression.None {
+				level = compression.LevelDefault
+			}
+			var data bytes.Buffer
+			opts := &WriterOptions{Version: version, Compression: compression.MakeFlags(method, level)}
+			err := Write(&data, seedResource(), opts)
+			if err != nil {
+				f.Fatalf("writing seed file: %v", err)
+			}
+			files = append(files, data.Bytes())
+		}
+	}
+	return files
+}
+
+// Fails if fn doesn't return in time, so inputs that make the parsers loop forever are caught too
+func runWithTimeout(t *testing.T, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func FuzzRead(f *testing.F) {
+	for _, file := range seedFiles(f) {
+		f.Add(file)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runWithTimeout(t, func() {
+			res, err := NewReaderWithOptions(bytes.NewReader(data), &fuzzReaderOptions).Read()
+			if err != nil {
+				return
+			}
+			// Whatever was read has to be writable again
+			err = Write(io.Discard, res, nil)
+			if err != nil {
+				t.Errorf("writing what was read: %v", err)
+			}
+		})
+	})
+}
+
+// Sections of the uncompressed seed files, for the section parsers
+func seedSections(f *testing.F) map[string][][]byte {
+	sections := make(map[string][][]byte)
+	for _, file := range seedFiles(f) {
+		reader := NewReader(bytes.NewReader(file))
+		_, err := reader.Read()
+		if err != nil {
+			f.Fatalf("reading seed file: %v", err)
+		}
+		if reader.metadata.CompressionFlags.Method() != compression.None {
+			continue
+		}
+
+		meta := reader.metadata
+		offset := len(file) - int(meta.StringsUncompressedSize+meta.NodesUncompressedSize+meta.AttributesUncompressedSize+meta.ValuesUncompressedSize+meta.KeysUncompressedSize)
+		for _, section := range []struct {
+			name string
+			size uint32
+		}{
+			{"names", meta.StringsUncompressedSize},
+			{"nodes", meta.NodesUncompressedSize},
+			{"attributes", meta.AttributesUncompressedSize},
+			{"values", meta.ValuesUncompressedSize},
+			{"keys", meta.KeysUncompressedSize},
+		} {
+			sections[section.name] = append(sections[section.name], file[offset:offset+int(section.size)])
+			offset += int(section.size)
+		}
+	}
+	return sections
+}
+
+// Reader with the names of the seed resource, for parsing the sections after the names
+func seedNamesReader(f *testing.F) *Reader {
+	file := seedFiles(f)[0]
+	reader := NewReader(bytes.NewReader(file))
+	_, err := reader.Read()
+	if err != nil {
+		f.Fatalf("reading seed file: %v", err)
+	}
+	return reader
+}
+
+func FuzzReadNames(f *testing.F) {
+	for _, section := range seedSections(f)["names"] {
+		f.Add(section)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runWithTimeout(t, func() {
+			reader := NewReaderWithOptions(nil, &fuzzReaderOptions)
+			reader.readNames(data)
+		})
+	})
+}
+
+func FuzzReadNodes(f *testing.F) {
+	for _, section := range seedSections(f)["nodes"] {
+		f.Add(section)
+	}
+	names := seedNamesReader(f).names
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runWithTimeout(t, func() {
+			reader := NewReaderWithOptions(nil, &fuzzReaderOptions)
+			reader.names = names
+			if reader.readNodes(data) != nil {
+				return
+			}
+			for i, node := range reader.nodes {
+				if node.ParentIndex >= i {
+					t.Fatalf("node %d has parent %d", i, node.ParentIndex)
+				}
+			}
+		})
+	})
+}
+
+func FuzzReadAttributes(f *testing.F) {
+	for _, section := range seedSections(f)["attributes"] {
+		f.Add(section)
+	}
+	names := seedNamesReader(f).names
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runWithTimeout(t, func() {
+			reader := NewReaderWithOptions(nil, &fuzzReaderOptions)
+			reader.names = names
+			reader.readAttributesV3(data)
+		})
+	})
+}
+
+// Values are only decoded while building the resource, so this fuzzes them with the seed's other sections
+func FuzzReadValues(f *testing.F) {
+	for _, section := range seedSections(f)["values"] {
+		f.Add(section)
+	}
+	seed := seedNamesReader(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runWithTimeout(t, func() {
+			reader := NewReaderWithOptions(nil, &fuzzReaderOptions)
+			reader.names = seed.names
+			reader.nodes = seed.nodes
+			reader.attributes = seed.attributes
+			reader.values = data
+			reader.buildResource()
+		})
+	})
+}
@@ -0,0 +1,718 @@
+package lsf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Wrapper for Read to handle file opening, also takes "archive.pak:path/in/archive" paths
+func ReadFile(filename string) (*resource.Resource, error) {
+	file, err := pak.OpenPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewReader(file).Read()
+}
+
+// Read reads an LSF from any stream, front to back without seeking, so pipes and stdin work too
+func Read(stream io.Reader) (*resource.Resource, error) {
+	return NewReader(stream).Read()
+}
+
+/*
+ReaderOptions limits what a file can make the reader do, so corrupt or malicious files fail with an error
+instead of exhausting memory, and what parts of it are built. Zero values mean no limit.
+*/
+type ReaderOptions struct {
+	// Largest decompressed size of any one section
+	MaxSectionSize uint64
+	// Largest decompressed size of all the sections together
+	MaxTotalSize uint64
+	// Deepest nesting of nodes below a region, and of translated string arguments
+	MaxDepth int
+	// Most sections decompressed at the same time, 1 decompresses them one after another. Callers already
+	// reading several files in parallel can use that to avoid starting more work than there are CPUs.
+	Concurrency int
+
+	// Regions to build, all of them when empty
+	Regions []string
+	// Nodes to build, as paths like "Templates/GameObjects[MapKey=...]" (how diff names nodes with a key or GUID):
+	// the region, then node names, each with any attribute values the node must have. Nodes on a path are built with everything
+	// below them and the nodes above them, but none of the other nodes. Everything is built when empty.
+	Paths []string
+	// Leaves attribute values undecoded until they're used, see resource.NewLazyAttribute. Lazy values keep
+	// the file's values section in memory.
+	LazyValues bool
+}
+
+// DefaultReaderOptions are used when no options are given, they're far beyond anything in the game's files
+var DefaultReaderOptions = ReaderOptions{
+	MaxSectionSize: 512 << 20,
+	MaxTotalSize:   1 << 30,
+	MaxDepth:       256,
+}
+
+/*
+NewReader reads from any stream. The sections are read in order, using the sizes in the metadata, so the
+stream doesn't have to be seekable. If it is, sections are also checked against the file size before
+anything is allocated for them.
+*/
+func NewReader(stream io.Reader) *Reader {
+	return NewReaderWithOptions(stream, nil)
+}
+
+func NewReaderWithOptions(stream io.Reader, opts *ReaderOptions) *Reader {
+	if opts == nil {
+		opts = &DefaultReaderOptions
+	}
+	return &Reader{
+		stream: stream,
+		opts:   *opts,
+	}
+}
+
+func (r *Reader) Read() (*resource.Resource, error) {
+	// MaxTotalSize is per file, the stream can hold several
+	r.totalSize = 0
+
+	// Bad paths fail before anything is read
+	var err error
+	r.paths, err = parsePaths(r.opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := newFileReader(r.stream)
+	if err != nil {
+		return nil, newDecodeError("header", 0, err)
+	}
+
+	magic, err := r.readMagic(reader)
+	if err != nil {
+		return nil, newDecodeError("header", 0, err)
+	}
+
+	r.version = magic.Version
+
+	err = r.readHeader(reader)
+	if err != nil {
+		return nil, newDecodeError("header", int64(binary.Size(Magic{})), err)
+	}
+
+	err = r.readMetadata(reader)
+	if err != nil {
+		return nil, newDecodeError("metadata", int64(binary.Size(Magic{})+binary.Size(Header{})), err)
+	}
+
+	err = r.readSections(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.buildResource()
+}
+
+// Version returns the LSF version of the file that was read
+func (r *Reader) Version() uint32 {
+	return r.version
+}
+
+// CompressionFlags returns the compression settings of the file that was read
+func (r *Reader) CompressionFlags() compression.Flags {
+	if r.metadata == nil {
+		return 0
+	}
+	return r.metadata.CompressionFlags
+}
+
+///////////////////////////////
+//// File Section Handlers ////
+///////////////////////////////
+
+func (r *Reader) readMagic(reader *fileReader) (*Magic, error) {
+	magic := &Magic{}
+	err := binary.Read(reader, binary.LittleEndian, magic)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedMagic := binary.LittleEndian.Uint32(Signature)
+	if magic.Magic != expectedMagic {
+		return nil, fmt.Errorf("invalid LSF signature; expected %08X, got %08X", expectedMagic, magic.Magic)
+	}
+
+	if magic.Version < VersionMin || magic.Version > VersionMax {
+		return nil, fmt.Errorf("LSF version %d is not supported (BG3 requires version 5-7, got %d)", magic.Version, magic.Version)
+	}
+
+	return magic, nil
+}
+
+func (r *Reader) readHeader(reader *fileReader) error {
+	header := &Header{}
+	err := binary.Read(reader, binary.LittleEndian, header)
+	if err != nil {
+		return err
+	}
+	r.gameVersion = unpackVersion64(header.EngineVersion)
+
+	// Duped lslib's logic for LSF files with missing engine version
+	if r.gameVersion.Major == 0 {
+		r.gameVersion.Major = 4
+		r.gameVersion.Minor = 0
+		r.gameVersion.Revision = 9
+		r.gameVersion.Build = 0
+	}
+
+	return nil
+}
+
+// Version 6+ files use V6 metadata (with Keys section). Older V5 metadata is widened to V6 with an empty Keys section.
+func (r *Reader) readMetadata(reader *fileReader) error {
+	if r.version < VersionBG3NodeKeys {
+		metaV5 := &MetadataV5{}
+		err := binary.Read(reader, binary.LittleEndian, metaV5)
+		if err != nil {
+			return err
+		}
+		r.metadata = &MetadataV6{
+			StringsUncompressedSize:    metaV5.StringsUncompressedSize,
+			StringsSizeOnDisk:          metaV5.StringsSizeOnDisk,
+			NodesUncompressedSize:      metaV5.NodesUncompressedSize,
+			NodesSizeOnDisk:            metaV5.NodesSizeOnDisk,
+			AttributesUncompressedSize: metaV5.AttributesUncompressedSize,
+			AttributesSizeOnDisk:       metaV5.AttributesSizeOnDisk,
+			ValuesUncompressedSize:     metaV5.ValuesUncompressedSize,
+			ValuesSizeOnDisk:           metaV5.ValuesSizeOnDisk,
+			CompressionFlags:           metaV5.CompressionFlags,
+			Unknown2:                   metaV5.Unknown2,
+			Unknown3:                   metaV5.Unknown3,
+			MetadataFormat:             metaV5.MetadataFormat,
+		}
+		return nil
+	}
+
+	meta := &MetadataV6{}
+	err := binary.Read(reader, binary.LittleEndian, meta)
+	if err != nil {
+		return err
+	}
+	r.metadata = meta
+	return nil
+}
+
+func (r *Reader) readSections(reader *fileReader) error {
+	meta := r.metadata
+
+	// Read every section first, they're stored one after another, then decompress them together
+	names, err := r.readSection(reader, "names", meta.StringsSizeOnDisk, meta.StringsUncompressedSize, false)
+	if err != nil {
+		return err
+	}
+	// BG3 always uses V3 format (extended) nodes
+	nodes, err := r.readSection(reader, "nodes", meta.NodesSizeOnDisk, meta.NodesUncompressedSize, true)
+	if err != nil {
+		return err
+	}
+	attrs, err := r.readSection(reader, "attributes", meta.AttributesSizeOnDisk, meta.AttributesUncompressedSize, true)
+	if err != nil {
+		return err
+	}
+	values, err := r.readSection(reader, "values", meta.ValuesSizeOnDisk, meta.ValuesUncompressedSize, true)
+	if err != nil {
+		return err
+	}
+	sections := []*rawSection{names, nodes, attrs, values}
+
+	// BG3 always uses MetadataKeysAndAdjacency so don't need to check metadata format.
+	// Uncompressed files have a zero size on disk, so check the uncompressed size instead.
+	var keys *rawSection
+	if meta.KeysUncompressedSize > 0 {
+		keys, err = r.readSection(reader, "keys", meta.KeysSizeOnDisk, meta.KeysUncompressedSize, true)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, keys)
+	}
+
+	err = r.decompressSections(sections)
+	if err != nil {
+		return err
+	}
+
+	err = r.readNames(names.data)
+	if err != nil {
+		return err
+	}
+	err = r.readNodes(nodes.data)
+	if err != nil {
+		return err
+	}
+	err = r.readAttributesV3(attrs.data)
+	if err != nil {
+		return err
+	}
+	r.values = values.data
+	if keys != nil {
+		err = r.readKeys(keys.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads a section without decompressing it yet, errors point at where the section starts in the file
+func (r *Reader) readSection(reader *fileReader, section string, sizeOnDisk, uncompressedSize uint32, allowChunked bool) (*rawSection, error) {
+	offset := reader.offset
+
+	// Check the sizes before allocating anything for the section
+	if r.opts.MaxSectionSize > 0 && uint64(uncompressedSize) > r.opts.MaxSectionSize {
+		return nil, newDecodeError(section, offset, fmt.Errorf("section size %d is over the limit of %d", uncompressedSize, r.opts.MaxSectionSize))
+	}
+	r.totalSize += uint64(uncompressedSize)
+	if r.opts.MaxTotalSize > 0 && r.totalSize > r.opts.MaxTotalSize {
+		return nil, newDecodeError(section, offset, fmt.Errorf("total size of the sections is over the limit of %d", r.opts.MaxTotalSize))
+	}
+	sizeInFile := sizeOnDisk
+	if sizeOnDisk == 0 || r.metadata.CompressionFlags.Method() == compression.None {
+		sizeInFile = uncompressedSize
+	}
+	if reader.size >= 0 && int64(sizeInFile) > reader.size-offset {
+		return nil, newDecodeError(section, offset, fmt.Errorf("section of %d bytes runs past the end of the file", sizeInFile))
+	}
+
+	data, compressed, err := r.readRawSection(reader, sizeOnDisk, uncompressedSize)
+	if err != nil {
+		return nil, newDecodeError(section, offset, err)
+	}
+	return &rawSection{
+		name:             section,
+		offset:           offset,
+		data:             data,
+		uncompressedSize: uncompressedSize,
+		compressed:       compressed,
+		allowChunked:     allowChunked,
+	}, nil
+}
+
+func (r *Reader) readNames(data []byte) error {
+	reader := newBinaryReader(data)
+	numHashEntries, err := reader.readUint32()
+	if err != nil {
+		return newDecodeError("names", 0, err)
+	}
+	// Every hash entry takes at least 2 bytes
+	if uint64(numHashEntries)*2 > uint64(reader.Len()) {
+		return newDecodeError("names", 0, fmt.Errorf("%d hash entries don't fit in %d bytes", numHashEntries, len(data)))
+	}
+
+	r.names = make([][]string, numHashEntries)
+	for i := uint32(0); i < numHashEntries; i++ {
+		offset := reader.Offset()
+		numStrings, err := reader.readUint16()
+		if err != nil {
+			return newDecodeError("names", offset, err)
+		}
+
+		hash := make([]string, 0, numStrings)
+		for j := uint16(0); j < numStrings; j++ {
+			offset = reader.Offset()
+			nameLen, err := reader.readUint16()
+			if err != nil {
+				return newDecodeError("names", offset, err)
+			}
+			if int(nameLen) > reader.Len() {
+				return newDecodeError("names", offset, fmt.Errorf("name of %d bytes runs past the end of the section", nameLen))
+			}
+			nameBytes, err := reader.next(int(nameLen))
+			if err != nil {
+				return newDecodeError("names", offset, err)
+			}
+			hash = append(hash, string(nameBytes))
+		}
+		r.names[i] = hash
+	}
+
+	return nil
+}
+
+// Looks up a name by its packed hash table index (bucket in the high 16 bits, position in the low 16 bits)
+func (r *Reader) name(packed uint32) (string, error) {
+	index, offset := int(packed>>16), int(packed&0xffff)
+	if index >= len(r.names) || offset >= len(r.names[index]) {
+		return "", fmt.Errorf("name %d/%d doesn't exist", index, offset)
+	}
+	return r.names[index][offset], nil
+}
+
+func (r *Reader) readNodes(data []byte) error {
+	reader := newBinaryReader(data)
+	r.nodes = make([]nodeInfo, 0, len(data)/nodeEntrySize)
+
+	for reader.Len() > 0 {
+		offset := reader.Offset()
+		index := len(r.nodes)
+		fail := func(err error) error {
+			return &DecodeError{Section: "nodes", Node: index, Offset: offset, Err: err}
+		}
+
+		entry, err := reader.readNodeEntry()
+		if err != nil {
+			return fail(err)
+		}
+
+		name, err := r.name(entry.NameHashTableIndex)
+		if err != nil {
+			return fail(err)
+		}
+		// Parents always come before their children, which also rules out cycles
+		if entry.ParentIndex < -1 || int(entry.ParentIndex) >= index {
+			return fail(fmt.Errorf("parent index %d is invalid", entry.ParentIndex))
+		}
+
+		r.nodes = append(r.nodes, nodeInfo{
+			ParentIndex:         int(entry.ParentIndex),
+			Name:                name,
+			FirstAttributeIndex: int(entry.FirstAttributeIndex),
+		})
+	}
+
+	return nil
+}
+
+func (r *Reader) readAttributesV3(data []byte) error {
+	reader := newBinaryReader(data)
+	r.attributes = make([]attributeInfo, 0, len(data)/attributeEntrySize)
+
+	for reader.Len() > 0 {
+		offset := reader.Offset()
+		entry, err := reader.readAttributeEntry()
+		if err != nil {
+			return newDecodeError("attributes", offset, err)
+		}
+
+		name, err := r.name(entry.NameHashTableIndex)
+		if err != nil {
+			return newDecodeError("attributes", offset, err)
+		}
+
+		attrInfo := attributeInfo{
+			Name:               name,
+			TypeId:             entry.TypeAndLength & 0x3f,
+			Length:             entry.TypeAndLength >> 6,
+			DataOffset:         entry.Offset,
+			NextAttributeIndex: int(entry.NextAttributeIndex),
+		}
+		if attrInfo.TypeId > uint32(resource.AttrMax) {
+			return &DecodeError{Section: "attributes", Node: -1, Attribute: name, Offset: offset, Err: fmt.Errorf("unknown attribute type %d", attrInfo.TypeId)}
+		}
+
+		r.attributes = append(r.attributes, attrInfo)
+	}
+
+	return nil
+}
+
+func (r *Reader) readKeys(data []byte) error {
+	reader := newBinaryReader(data)
+
+	for reader.Len() > 0 {
+		offset := reader.Offset()
+		entry, err := reader.readKeyEntry()
+		if err != nil {
+			return newDecodeError("keys", offset, err)
+		}
+
+		nodeIdx := int(entry.NodeIndex)
+		if nodeIdx >= len(r.nodes) {
+			return newDecodeError("keys", offset, fmt.Errorf("node %d doesn't exist", nodeIdx))
+		}
+		keyAttribute, err := r.name(entry.KeyName)
+		if err != nil {
+			return &DecodeError{Section: "keys", Node: nodeIdx, Offset: offset, Err: err}
+		}
+		r.nodes[nodeIdx].KeyAttribute = keyAttribute
+	}
+
+	return nil
+}
+
+func (r *Reader) buildResource() (*resource.Resource, error) {
+	res := &resource.Resource{
+		Metadata: resource.LSMetadata{
+			MajorVersion: r.gameVersion.Major,
+			MinorVersion: r.gameVersion.Minor,
+			Revision:     r.gameVersion.Revision,
+			BuildNumber:  r.gameVersion.Build,
+		},
+		Regions: make(map[string]*resource.Region),
+	}
+
+	selected, err := r.selectNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Build nodes
+	r.nodeInstances = make([]*resource.Node, len(r.nodes))
+	valueReader := newBinaryReader(r.values)
+	depths := make([]int, len(r.nodes))
+	// Node that last visited each attribute (plus one), to catch attribute chains that loop
+	visitedBy := make([]int, len(r.attributes))
+
+	for i := range r.nodes {
+		nodeInfo := &r.nodes[i]
+		if nodeInfo.ParentIndex != -1 {
+			depths[i] = depths[nodeInfo.ParentIndex] + 1
+			if r.opts.MaxDepth > 0 && depths[i] > r.opts.MaxDepth {
+				return nil, &DecodeError{Section: "nodes", Node: i, Offset: -1, Err: fmt.Errorf("nodes are nested deeper than the limit of %d", r.opts.MaxDepth)}
+			}
+		}
+
+		if selected != nil && !selected[i] {
+			continue
+		}
+
+		var node *resource.Node
+		if nodeInfo.ParentIndex == -1 {
+			// Root region
+			region := &resource.Region{
+				Node: resource.Node{
+					Name:       nodeInfo.Name,
+					Attributes: make(map[string]*resource.NodeAttribute),
+					Children:   make(map[string][]*resource.Node),
+				},
+			}
+			region.RegionName = region.Name
+			region.KeyAttribute = nodeInfo.KeyAttribute
+			node = &region.Node
+			r.nodeInstances[i] = node
+			res.AddRegion(region)
+		} else { // Child node
+			node = &resource.Node{
+				Name:       nodeInfo.Name,
+				Parent:     r.nodeInstances[nodeInfo.ParentIndex],
+				Attributes: make(map[string]*resource.NodeAttribute),
+				Children:   make(map[string][]*resource.Node),
+			}
+			node.KeyAttribute = nodeInfo.KeyAttribute
+			r.nodeInstances[i] = node
+			r.nodeInstances[nodeInfo.ParentIndex].AppendChild(node)
+		}
+
+		// Read attributes, following the chain keeps them in file order
+		attrIdx := nodeInfo.FirstAttributeIndex
+		for attrIdx != -1 {
+			if attrIdx < 0 || attrIdx >= len(r.attributes) {
+				return nil, &DecodeError{Section: "attributes", Node: i, Offset: -1, Err: fmt.Errorf("attribute %d doesn't exist", attrIdx)}
+			}
+			if visitedBy[attrIdx] == i+1 {
+				return nil, &DecodeError{Section: "attributes", Node: i, Offset: -1, Err: fmt.Errorf("attribute chain loops back to attribute %d", attrIdx)}
+			}
+			visitedBy[attrIdx] = i + 1
+			attrInfo := &r.attributes[attrIdx]
+
+			var attrValue *resource.NodeAttribute
+			if r.opts.LazyValues {
+				attrValue = r.lazyAttribute(i, *attrInfo)
+			} else {
+				attrValue, err = r.decodeAttribute(i, attrInfo, valueReader)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			node.SetAttribute(attrInfo.Name, attrValue)
+
+			attrIdx = attrInfo.NextAttributeIndex
+		}
+	}
+
+	return res, nil
+}
+
+// Decodes an attribute's value, errors point at the node and where the value is in the values section
+func (r *Reader) decodeAttribute(nodeIndex int, attrInfo *attributeInfo, valueReader *binaryReader) (*resource.NodeAttribute, error) {
+	fail := func(err error) error {
+		return &DecodeError{Section: "values", Node: nodeIndex, Attribute: attrInfo.Name, Offset: int64(attrInfo.DataOffset), Err: err}
+	}
+
+	if uint64(attrInfo.DataOffset)+uint64(attrInfo.Length) > uint64(len(valueReader.data)) {
+		return nil, fail(fmt.Errorf("%d bytes of data run past the end of the section", attrInfo.Length))
+	}
+	valueReader.Seek(int(attrInfo.DataOffset))
+	attr, err := r.readAttribute(resource.AttributeType(attrInfo.TypeId), valueReader, attrInfo.Length)
+	if err != nil {
+		return nil, fail(err)
+	}
+	return attr, nil
+}
+
+// An attribute that's decoded when it's loaded, holding on to the values section until then. The section and
+// attribute are captured now, as the Reader's are replaced if it reads another file first.
+func (r *Reader) lazyAttribute(nodeIndex int, attrInfo attributeInfo) *resource.NodeAttribute {
+	values := r.values
+	return resource.NewLazyAttribute(resource.AttributeType(attrInfo.TypeId), func() (interface{}, error) {
+		attr, err := r.decodeAttribute(nodeIndex, &attrInfo, newBinaryReader(values))
+		if err != nil {
+			return nil, err
+		}
+		return attr.Value, nil
+	})
+}
+
+func (r *Reader) readAttribute(attrType resource.AttributeType, reader *binaryReader, length uint32) (*resource.NodeAttribute, error) {
+	attr := &resource.NodeAttribute{Type: attrType}
+
+	switch attrType {
+	case resource.AttrString, resource.AttrPath, resource.AttrFixedString, resource.AttrLSString, resource.AttrWString, resource.AttrLSWString:
+		value, err := r.readString(reader, int(length))
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = value
+
+	case resource.AttrTranslatedString:
+		// BG3 always uses the new format (version field, no value field)
+		ts := &resource.TranslatedString{}
+		var err error
+		ts.Version, err = reader.readUint16()
+		if err != nil {
+			return nil, err
+		}
+		ts.Handle, err = r.readLengthPrefixedString(reader)
+		if err != nil {
+			return nil, fmt.Errorf("handle: %w", err)
+		}
+		attr.Value = ts
+
+	case resource.AttrTranslatedFSString:
+		fs, err := r.readTranslatedFSString(reader, 0)
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = fs
+
+	case resource.AttrScratchBuffer:
+		buf, err := reader.readBytes(int(length))
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = buf
+
+	default:
+		// Use BinUtils equivalent
+		value, err := readAttributeValue(attrType, reader)
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = value
+	}
+
+	return attr, nil
+}
+
+// Arguments are strings too, depth is how deep this one is nested
+func (r *Reader) readTranslatedFSString(reader *binaryReader, depth int) (*resource.TranslatedFSString, error) {
+	if r.opts.MaxDepth > 0 && depth > r.opts.MaxDepth {
+		return nil, fmt.Errorf("arguments are nested deeper than the limit of %d", r.opts.MaxDepth)
+	}
+
+	// BG3 always uses the new format (version field, no value field)
+	fs := &resource.TranslatedFSString{}
+	var err error
+	fs.Version, err = reader.readUint16()
+	if err != nil {
+		return nil, err
+	}
+
+	fs.Handle, err = r.readLengthPrefixedString(reader)
+	if err != nil {
+		return nil, fmt.Errorf("handle: %w", err)
+	}
+
+	argCount, err := reader.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	// Every argument takes at least 18 bytes
+	if argCount < 0 || int64(argCount)*18 > int64(reader.Len()) {
+		return nil, fmt.Errorf("invalid argument count %d", argCount)
+	}
+	fs.Arguments = make([]resource.TranslatedFSStringArgument, argCount)
+
+	for i := int32(0); i < argCount; i++ {
+		arg := resource.TranslatedFSStringArgument{}
+		arg.Key, err = r.readLengthPrefixedString(reader)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d key: %w", i, err)
+		}
+
+		argString, err := r.readTranslatedFSString(reader, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d string: %w", i, err)
+		}
+		arg.String = *argString
+
+		arg.Value, err = r.readLengthPrefixedString(reader)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d value: %w", i, err)
+		}
+
+		fs.Arguments[i] = arg
+	}
+
+	return fs, nil
+}
+
+// Reads an int32 length followed by a string of that length
+func (r *Reader) readLengthPrefixedString(reader *binaryReader) (string, error) {
+	length, err := reader.readInt32()
+	if err != nil {
+		return "", err
+	}
+	return r.readString(reader, int(length))
+}
+
+func (r *Reader) readString(reader *binaryReader, length int) (string, error) {
+	if length == 0 {
+		return "", nil
+	}
+	if length < 0 || length > reader.Len() {
+		return "", fmt.Errorf("invalid string length %d (%d bytes left)", length, reader.Len())
+	}
+
+	data, err := reader.next(length)
+	if err != nil {
+		return "", err
+	}
+
+	// Not always strictly null-terminated, so the last byte is skipped whatever it is
+	data = data[:length-1]
+
+	// Remove trailing nulls
+	lastNull := len(data)
+	for lastNull > 0 && data[lastNull-1] == 0 {
+		lastNull--
+	}
+
+	return string(data[:lastNull]), nil
+}
+
+func unpackVersion64(packed int64) PackedVersion {
+	return PackedVersion{
+		Major:    uint32((packed >> 55) & 0x7f),
+		Minor:    uint32((packed >> 47) & 0xff),
+		Revision: uint32((packed >> 31) & 0xffff),
+		Build:    uint32(packed & 0x7fffffff),
+	}
+}
@@ -0,0 +1,157 @@
+// Package lsf reads and writes the binary LSF format used by BG3.
+package lsf
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Divinity Engine version
+type PackedVersion struct {
+	Major    uint32
+	Minor    uint32
+	Revision uint32
+	Build    uint32
+}
+
+// Metadata format (BG3 only uses MetadataKeysAndAdjacency)
+type MetadataFormat uint32
+
+const (
+	MetadataNone             MetadataFormat = 0
+	MetadataKeysAndAdjacency MetadataFormat = 1
+	MetadataNone2            MetadataFormat = 2
+)
+
+// LSOF file signature
+var Signature = []byte{'L', 'S', 'O', 'F'}
+
+// LSF format versions (BG3 only uses 5-7)
+const (
+	VersionBG3ExtendedHeader = 5
+	VersionBG3NodeKeys       = 6
+	VersionBG3Patch3         = 7
+	VersionMin               = 5
+	VersionMax               = 7
+)
+
+// Magic represents the magic file header
+type Magic struct {
+	Magic   uint32
+	Version uint32
+}
+
+// Header represents the header (v5+ only)
+type Header struct {
+	EngineVersion int64
+}
+
+// MetadataV5 represents the metadata format of version 5 files, which predate the Keys section
+type MetadataV5 struct {
+	StringsUncompressedSize    uint32
+	StringsSizeOnDisk          uint32
+	NodesUncompressedSize      uint32
+	NodesSizeOnDisk            uint32
+	AttributesUncompressedSize uint32
+	AttributesSizeOnDisk       uint32
+	ValuesUncompressedSize     uint32
+	ValuesSizeOnDisk           uint32
+	CompressionFlags           compression.Flags
+	Unknown2                   uint8
+	Unknown3                   uint16
+	MetadataFormat             MetadataFormat
+}
+
+// MetadataV6 represents BG3 metadata format (V6+ with Keys section)
+type MetadataV6 struct {
+	StringsUncompressedSize    uint32
+	StringsSizeOnDisk          uint32
+	KeysUncompressedSize       uint32
+	KeysSizeOnDisk             uint32
+	NodesUncompressedSize      uint32
+	NodesSizeOnDisk            uint32
+	AttributesUncompressedSize uint32
+	AttributesSizeOnDisk       uint32
+	ValuesUncompressedSize     uint32
+	ValuesSizeOnDisk           uint32
+	CompressionFlags           compression.Flags
+	Unknown2                   uint8
+	Unknown3                   uint16
+	MetadataFormat             MetadataFormat
+}
+
+// NodeEntryV3 represents BG3 node format (always uses V3 with extended nodes)
+type NodeEntryV3 struct {
+	NameHashTableIndex  uint32
+	ParentIndex         int32
+	NextSiblingIndex    int32
+	FirstAttributeIndex int32
+}
+
+// AttributeEntryV3 represents BG3 attribute format (always uses V3 with adjacency data)
+type AttributeEntryV3 struct {
+	NameHashTableIndex uint32
+	TypeAndLength      uint32
+	NextAttributeIndex int32
+	Offset             uint32
+}
+
+// KeyEntry represents a key attribute entry
+type KeyEntry struct {
+	NodeIndex uint32
+	KeyName   uint32
+}
+
+// nodeInfo holds processed node information
+type nodeInfo struct {
+	ParentIndex         int
+	Name                string
+	FirstAttributeIndex int
+	KeyAttribute        string
+}
+
+// attributeInfo holds processed attribute information
+type attributeInfo struct {
+	Name               string
+	TypeId             uint32
+	Length             uint32
+	DataOffset         uint32
+	NextAttributeIndex int
+}
+
+// Reader reads LSF files (BG3-only)
+type Reader struct {
+	stream        io.Reader
+	opts          ReaderOptions
+	paths         [][]pathStep
+	totalSize     uint64
+	version       uint32
+	gameVersion   PackedVersion
+	metadata      *MetadataV6 // BG3 always uses V6
+	names         [][]string
+	nodes         []nodeInfo
+	attributes    []attributeInfo
+	nodeInstances []*resource.Node
+	values        []byte
+}
+
+// Writer writes LSF files (BG3-only)
+type Writer struct {
+	stream             io.Writer
+	version            uint32
+	compressionFlags   compression.Flags
+	order              resource.Order
+	sortKeys           map[string]string
+	sorter             *resource.Sorter
+	names              [][]string
+	nodes              *bytes.Buffer
+	attributes         *bytes.Buffer
+	values             *bytes.Buffer
+	keys               *bytes.Buffer
+	nextSiblings       []int32
+	nextNodeIndex      int
+	nextAttributeIndex int
+}
@@ -0,0 +1,470 @@
+package lsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Number of buckets in the names hash table (same as lslib)
+const nameHashBuckets = 0x200
+
+// Attribute lengths share a uint32 with the 6 bit type id
+const maxAttributeLength = 1<<26 - 1
+
+// WriterOptions configures the LSF output
+type WriterOptions struct {
+	// LSF version to write, from VersionMin to VersionMax. Defaults to VersionMax.
+	Version uint32
+	// Compression of the sections. The zero value writes them uncompressed.
+	Compression compression.Flags
+	// Order of regions, attributes and children, see resource.Order
+	Order resource.Order
+	// Node name -> attribute that same-named siblings are sorted by first, see resource.Sorter
+	SortKeys map[string]string
+}
+
+// DefaultWriterOptions are used when no options are given (lslib's defaults for BG3)
+var DefaultWriterOptions = WriterOptions{
+	Version:     VersionMax,
+	Compression: compression.MakeFlags(compression.LZ4, compression.LevelDefault),
+}
+
+// Wrapper for Write to handle file creation
+func WriteFile(filename string, res *resource.Resource, opts *WriterOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Write(file, res, opts)
+}
+
+func Write(w io.Writer, res *resource.Resource, opts *WriterOptions) error {
+	return NewWriter(w, opts).Write(res)
+}
+
+func NewWriter(stream io.Writer, opts *WriterOptions) *Writer {
+	if opts == nil {
+		opts = &DefaultWriterOptions
+	}
+
+	version := opts.Version
+	if version == 0 {
+		version = VersionMax
+	}
+
+	return &Writer{
+		stream:           stream,
+		version:          version,
+		compressionFlags: opts.Compression,
+		order:            opts.Order,
+		sortKeys:         opts.SortKeys,
+	}
+}
+
+func (w *Writer) Write(res *resource.Resource) error {
+	if w.version < VersionMin || w.version > VersionMax {
+		return fmt.Errorf("LSF version %d is not supported (BG3 requires version 5-7, got %d)", w.version, w.version)
+	}
+
+	w.names = make([][]string, nameHashBuckets)
+	w.nodes = &bytes.Buffer{}
+	w.attributes = &bytes.Buffer{}
+	w.values = &bytes.Buffer{}
+	w.keys = &bytes.Buffer{}
+	w.nextNodeIndex = 0
+	w.nextAttributeIndex = 0
+	// Node digests are cached while sorting, so each resource gets its own sorter
+	w.sorter = &resource.Sorter{Keys: w.sortKeys}
+
+	// The sections have to be built up front, as the metadata before them holds their sizes
+	w.computeSiblingIndices(res)
+	for _, regionName := range res.RegionNamesInOrder(w.order) {
+		err := w.writeNode(&res.Regions[regionName].Node, -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	namesData, err := w.writeNames()
+	if err != nil {
+		return err
+	}
+
+	// Names are never chunked, the other sections always are
+	sections := make([][]byte, 5)
+	for i, section := range []struct {
+		data    []byte
+		chunked bool
+	}{
+		{namesData, false},
+		{w.nodes.Bytes(), true},
+		{w.attributes.Bytes(), true},
+		{w.values.Bytes(), true},
+		{w.keys.Bytes(), true},
+	} {
+		sections[i], err = w.compress(section.data, section.chunked)
+		if err != nil {
+			return err
+		}
+	}
+
+	meta := &MetadataV6{
+		StringsUncompressedSize:    uint32(len(namesData)),
+		StringsSizeOnDisk:          w.sizeOnDisk(sections[0]),
+		NodesUncompressedSize:      uint32(w.nodes.Len()),
+		NodesSizeOnDisk:            w.sizeOnDisk(sections[1]),
+		AttributesUncompressedSize: uint32(w.attributes.Len()),
+		AttributesSizeOnDisk:       w.sizeOnDisk(sections[2]),
+		ValuesUncompressedSize:     uint32(w.values.Len()),
+		ValuesSizeOnDisk:           w.sizeOnDisk(sections[3]),
+		KeysUncompressedSize:       uint32(w.keys.Len()),
+		KeysSizeOnDisk:             w.sizeOnDisk(sections[4]),
+		CompressionFlags:           w.compressionFlags,
+		MetadataFormat:             MetadataKeysAndAdjacency,
+	}
+
+	err = w.writeMagic()
+	if err != nil {
+		return err
+	}
+
+	err = w.writeHeader(res)
+	if err != nil {
+		return err
+	}
+
+	err = w.writeMetadata(meta)
+	if err != nil {
+		return err
+	}
+
+	return w.writeSections(sections)
+}
+
+///////////////////////////////
+//// File Section Handlers ////
+///////////////////////////////
+
+func (w *Writer) writeMagic() error {
+	magic := &Magic{
+		Magic:   binary.LittleEndian.Uint32(Signature),
+		Version: w.version,
+	}
+	return binary.Write(w.stream, binary.LittleEndian, magic)
+}
+
+func (w *Writer) writeHeader(res *resource.Resource) error {
+	header := &Header{
+		EngineVersion: packVersion64(PackedVersion{
+			Major:    res.Metadata.MajorVersion,
+			Minor:    res.Metadata.MinorVersion,
+			Revision: res.Metadata.Revision,
+			Build:    res.Metadata.BuildNumber,
+		}),
+	}
+	return binary.Write(w.stream, binary.LittleEndian, header)
+}
+
+func (w *Writer) writeMetadata(meta *MetadataV6) error {
+	if w.version < VersionBG3NodeKeys {
+		// V5 metadata has no Keys section
+		metaV5 := &MetadataV5{
+			StringsUncompressedSize:    meta.StringsUncompressedSize,
+			StringsSizeOnDisk:          meta.StringsSizeOnDisk,
+			NodesUncompressedSize:      meta.NodesUncompressedSize,
+			NodesSizeOnDisk:            meta.NodesSizeOnDisk,
+			AttributesUncompressedSize: meta.AttributesUncompressedSize,
+			AttributesSizeOnDisk:       meta.AttributesSizeOnDisk,
+			ValuesUncompressedSize:     meta.ValuesUncompressedSize,
+			ValuesSizeOnDisk:           meta.ValuesSizeOnDisk,
+			CompressionFlags:           meta.CompressionFlags,
+			MetadataFormat:             meta.MetadataFormat,
+		}
+		return binary.Write(w.stream, binary.LittleEndian, metaV5)
+	}
+
+	return binary.Write(w.stream, binary.LittleEndian, meta)
+}
+
+// Uncompressed sections are flagged by a size on disk of 0
+func (w *Writer) sizeOnDisk(section []byte) uint32 {
+	if w.compressionFlags.Method() == compression.None {
+		return 0
+	}
+	return uint32(len(section))
+}
+
+// Same section order as the reader: names, nodes, attributes, values, keys
+func (w *Writer) writeSections(sections [][]byte) error {
+	if w.version < VersionBG3NodeKeys {
+		sections = sections[:4]
+	}
+
+	for _, section := range sections {
+		_, err := w.stream.Write(section)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) writeNames() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writeUint32(buf, uint32(len(w.names)))
+
+	for _, hash := range w.names {
+		writeUint16(buf, uint16(len(hash)))
+		for _, name := range hash {
+			if len(name) > 0xffff {
+				return nil, fmt.Errorf("name is too long for the LSF names table (%d bytes)", len(name))
+			}
+			writeUint16(buf, uint16(len(name)))
+			buf.WriteString(name)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Adds a name to the hash table and returns its packed (bucket << 16 | offset) index
+func (w *Writer) addName(name string) (uint32, error) {
+	bucket := nameHashBucket(name)
+	for i, existing := range w.names[bucket] {
+		if existing == name {
+			return uint32(bucket<<16 | i), nil
+		}
+	}
+
+	if len(w.names[bucket]) >= 0xffff {
+		return 0, fmt.Errorf("too many names in LSF hash bucket %d", bucket)
+	}
+	w.names[bucket] = append(w.names[bucket], name)
+	return uint32(bucket<<16 | (len(w.names[bucket]) - 1)), nil
+}
+
+// The game takes the bucket from the file so any hash works, but it has to be stable for the output to be deterministic
+func nameHashBucket(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	hash := h.Sum32()
+	return int((hash & 0x1ff) ^ ((hash >> 9) & 0x1ff) ^ ((hash >> 18) & 0x1ff) ^ ((hash >> 27) & 0x1ff))
+}
+
+/////////////////////////
+//// Node Handlers //////
+/////////////////////////
+
+// Node indices are assigned depth first, so the next sibling of each node is known before it's written
+func (w *Writer) computeSiblingIndices(res *resource.Resource) {
+	w.nextSiblings = make([]int32, 0)
+
+	regions := make([]*resource.Node, 0, len(res.Regions))
+	for _, regionName := range res.RegionNamesInOrder(w.order) {
+		regions = append(regions, &res.Regions[regionName].Node)
+	}
+	w.linkSiblings(regions)
+}
+
+func (w *Writer) linkSiblings(siblings []*resource.Node) {
+	lastIndex := -1
+	for _, node := range siblings {
+		index := len(w.nextSiblings)
+		w.nextSiblings = append(w.nextSiblings, -1)
+		if lastIndex != -1 {
+			w.nextSiblings[lastIndex] = int32(index)
+		}
+		lastIndex = index
+
+		w.linkSiblings(w.childNodes(node))
+	}
+}
+
+func (w *Writer) writeNode(node *resource.Node, parentIndex int) error {
+	nodeIndex := w.nextNodeIndex
+	w.nextNodeIndex++
+
+	nameIndex, err := w.addName(node.Name)
+	if err != nil {
+		return err
+	}
+
+	entry := &NodeEntryV3{
+		NameHashTableIndex:  nameIndex,
+		ParentIndex:         int32(parentIndex),
+		NextSiblingIndex:    w.nextSiblings[nodeIndex],
+		FirstAttributeIndex: -1,
+	}
+
+	attrNames := node.AttributeNamesInOrder(w.order)
+	if len(attrNames) > 0 {
+		entry.FirstAttributeIndex = int32(w.nextAttributeIndex)
+		err = w.writeAttributes(node, attrNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = binary.Write(w.nodes, binary.LittleEndian, entry)
+	if err != nil {
+		return err
+	}
+
+	if node.KeyAttribute != "" {
+		keyName, err := w.addName(node.KeyAttribute)
+		if err != nil {
+			return err
+		}
+		key := &KeyEntry{
+			NodeIndex: uint32(nodeIndex),
+			KeyName:   keyName,
+		}
+		err = binary.Write(w.keys, binary.LittleEndian, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range w.childNodes(node) {
+		err = w.writeNode(child, nodeIndex)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Attributes of a node are stored consecutively, each one linking to the next
+func (w *Writer) writeAttributes(node *resource.Node, attrNames []string) error {
+	for i, attrName := range attrNames {
+		attr := node.Attributes[attrName]
+		offset := w.values.Len()
+
+		err := w.writeAttribute(attr)
+		if err != nil {
+			return fmt.Errorf("attribute %q of node %q: %w", attrName, node.Name, err)
+		}
+
+		length := w.values.Len() - offset
+		if length > maxAttributeLength {
+			return fmt.Errorf("attribute %q of node %q is too large (%d bytes)", attrName, node.Name, length)
+		}
+
+		nameIndex, err := w.addName(attrName)
+		if err != nil {
+			return err
+		}
+
+		entry := &AttributeEntryV3{
+			NameHashTableIndex: nameIndex,
+			TypeAndLength:      uint32(attr.Type) | uint32(length)<<6,
+			NextAttributeIndex: -1,
+			Offset:             uint32(offset),
+		}
+		if i < len(attrNames)-1 {
+			entry.NextAttributeIndex = int32(w.nextAttributeIndex + 1)
+		}
+
+		err = binary.Write(w.attributes, binary.LittleEndian, entry)
+		if err != nil {
+			return err
+		}
+		w.nextAttributeIndex++
+	}
+
+	return nil
+}
+
+func (w *Writer) writeAttribute(attr *resource.NodeAttribute) error {
+	err := attr.Load()
+	if err != nil {
+		return err
+	}
+
+	switch attr.Type {
+	case resource.AttrString, resource.AttrPath, resource.AttrFixedString, resource.AttrLSString, resource.AttrWString, resource.AttrLSWString:
+		value, ok := attr.Value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value of type %T for %s attribute", attr.Value, attr.Type)
+		}
+		w.writeString(value)
+
+	case resource.AttrTranslatedString:
+		// BG3 always uses the new format (version field, no value field)
+		ts, ok := attr.Value.(*resource.TranslatedString)
+		if !ok {
+			return fmt.Errorf("invalid value of type %T for %s attribute", attr.Value, attr.Type)
+		}
+		writeUint16(w.values, ts.Version)
+		w.writeStringWithLength(ts.Handle)
+
+	case resource.AttrTranslatedFSString:
+		fs, ok := attr.Value.(*resource.TranslatedFSString)
+		if !ok {
+			return fmt.Errorf("invalid value of type %T for %s attribute", attr.Value, attr.Type)
+		}
+		w.writeTranslatedFSString(fs)
+
+	case resource.AttrScratchBuffer:
+		buf, ok := attr.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("invalid value of type %T for %s attribute", attr.Value, attr.Type)
+		}
+		w.values.Write(buf)
+
+	default:
+		return writeAttributeValue(attr.Type, attr.Value, w.values)
+	}
+
+	return nil
+}
+
+func (w *Writer) writeTranslatedFSString(fs *resource.TranslatedFSString) {
+	// BG3 always uses the new format (version field, no value field)
+	writeUint16(w.values, fs.Version)
+	w.writeStringWithLength(fs.Handle)
+
+	writeInt32(w.values, int32(len(fs.Arguments)))
+	for _, arg := range fs.Arguments {
+		w.writeStringWithLength(arg.Key)
+		w.writeTranslatedFSString(&arg.String)
+		w.writeStringWithLength(arg.Value)
+	}
+}
+
+// Strings are null terminated, the length stored elsewhere includes the terminator
+func (w *Writer) writeString(value string) {
+	w.values.WriteString(value)
+	w.values.WriteByte(0)
+}
+
+func (w *Writer) writeStringWithLength(value string) {
+	writeInt32(w.values, int32(len(value)+1))
+	w.writeString(value)
+}
+
+// Children are written grouped by name (like lslib), either sorted so the LSF is deterministic or in file order
+func (w *Writer) childNodes(node *resource.Node) []*resource.Node {
+	children := make([]*resource.Node, 0)
+	for _, childName := range node.ChildNamesInOrder(w.order) {
+		children = append(children, w.sorter.OrderSiblings(node.Children[childName], w.order)...)
+	}
+	return children
+}
+
+func packVersion64(version PackedVersion) int64 {
+	return int64(version.Major&0x7f)<<55 |
+		int64(version.Minor&0xff)<<47 |
+		int64(version.Revision&0xffff)<<31 |
+		int64(version.Build&0x7fffffff)
+}
@@ -0,0 +1,247 @@
+package lsj
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Wrapper for Read to handle file opening, also takes "archive.pak:path/in/archive" paths
+func ReadFile(filename string) (*resource.Resource, error) {
+	file, err := pak.OpenPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+func Read(r io.Reader) (*resource.Resource, error) {
+	decoder := json.NewDecoder(r)
+	// Keep numbers as text so 64 bit integers don't lose precision
+	decoder.UseNumber()
+
+	var doc struct {
+		Save struct {
+			Header struct {
+				Version string `json:"version"`
+			} `json:"header"`
+			Regions lsjObject `json:"regions"`
+		} `json:"save"`
+	}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LSJ document: %w", err)
+	}
+
+	res := &resource.Resource{
+		Regions: make(map[string]*resource.Region),
+	}
+
+	err = readLSJVersion(doc.Save.Header.Version, res)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, regionName := range doc.Save.Regions.keys {
+		var obj lsjObject
+		err = unmarshalJSON(doc.Save.Regions.values[regionName], &obj)
+		if err != nil {
+			return nil, fmt.Errorf("region %q: %w", regionName, err)
+		}
+
+		region := &resource.Region{RegionName: regionName}
+		region.Name = regionName
+		err = readLSJNode(&obj, &region.Node)
+		if err != nil {
+			return nil, fmt.Errorf("region %q: %w", regionName, err)
+		}
+		res.AddRegion(region)
+	}
+
+	return res, nil
+}
+
+func readLSJVersion(version string, res *resource.Resource) error {
+	if version == "" {
+		return nil
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) != 4 {
+		return fmt.Errorf("invalid version %q, expected major.minor.revision.build", version)
+	}
+
+	fields := []*uint32{
+		&res.Metadata.MajorVersion,
+		&res.Metadata.MinorVersion,
+		&res.Metadata.Revision,
+		&res.Metadata.BuildNumber,
+	}
+	for i, part := range parts {
+		val, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid version %q, expected major.minor.revision.build", version)
+		}
+		*fields[i] = uint32(val)
+	}
+
+	return nil
+}
+
+// Attributes are objects with a "type", children are arrays of node objects and "$key" is the key attribute.
+// Keys are read in document order, so the node keeps the order of the file.
+func readLSJNode(obj *lsjObject, node *resource.Node) error {
+	node.Attributes = make(map[string]*resource.NodeAttribute)
+	node.Children = make(map[string][]*resource.Node)
+
+	for _, key := range obj.keys {
+		value := bytes.TrimLeft(obj.values[key], " \t\r\n")
+		switch {
+		case key == lsjKeyName && bytes.HasPrefix(value, []byte(`"`)):
+			err := unmarshalJSON(value, &node.KeyAttribute)
+			if err != nil {
+				return fmt.Errorf("node %q, key attribute: %w", node.Name, err)
+			}
+
+		case bytes.HasPrefix(value, []byte("{")):
+			var attrObj map[string]interface{}
+			err := unmarshalJSON(value, &attrObj)
+			if err != nil {
+				return fmt.Errorf("node %q, attribute %q: %w", node.Name, key, err)
+			}
+			attr, err := readLSJAttribute(attrObj)
+			if err != nil {
+				return fmt.Errorf("node %q, attribute %q: %w", node.Name, key, err)
+			}
+			node.SetAttribute(key, attr)
+
+		case bytes.HasPrefix(value, []byte("[")):
+			var childObjs []*lsjObject
+			err := unmarshalJSON(value, &childObjs)
+			if err != nil {
+				return fmt.Errorf("node %q, child %q: %w", node.Name, key, err)
+			}
+			for _, childObj := range childObjs {
+				if childObj == nil {
+					return fmt.Errorf("node %q, child %q: expected an object", node.Name, key)
+				}
+				child := &resource.Node{Name: key, Parent: node}
+				err = readLSJNode(childObj, child)
+				if err != nil {
+					return err
+				}
+				node.AppendChild(child)
+			}
+
+		default:
+			return fmt.Errorf("node %q, %q: expected an attribute object or a list of children", node.Name, key)
+		}
+	}
+
+	return nil
+}
+
+func readLSJAttribute(obj map[string]interface{}) (*resource.NodeAttribute, error) {
+	attrType, ok := lsjAttributeType(obj["type"])
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute type %v", obj["type"])
+	}
+	attr := &resource.NodeAttribute{Type: attrType}
+
+	switch attrType {
+	case resource.AttrTranslatedString:
+		ts := &resource.TranslatedString{
+			Handle: lsjString(obj["handle"]),
+			Value:  lsjString(obj["value"]),
+		}
+		if version, ok := obj["version"]; ok {
+			val, err := strconv.ParseUint(lsjString(version), 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TranslatedString version %v", version)
+			}
+			ts.Version = uint16(val)
+		}
+		attr.Value = ts
+
+	case resource.AttrTranslatedFSString:
+		fs, err := readLSJTranslatedFSString(obj)
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = fs
+
+	default:
+		value, err := resource.ParseAttributeValue(attrType, lsjString(obj["value"]))
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = value
+	}
+
+	return attr, nil
+}
+
+func readLSJTranslatedFSString(obj map[string]interface{}) (*resource.TranslatedFSString, error) {
+	fs := &resource.TranslatedFSString{
+		Handle:    lsjString(obj["handle"]),
+		Value:     lsjString(obj["value"]),
+		Arguments: make([]resource.TranslatedFSStringArgument, 0),
+	}
+
+	args, _ := obj["arguments"].([]interface{})
+	for _, item := range args {
+		argObj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid TranslatedFSString argument")
+		}
+
+		stringObj, _ := argObj["string"].(map[string]interface{})
+		str, err := readLSJTranslatedFSString(stringObj)
+		if err != nil {
+			return nil, err
+		}
+
+		fs.Arguments = append(fs.Arguments, resource.TranslatedFSStringArgument{
+			Key:    lsjString(argObj["key"]),
+			Value:  lsjString(argObj["value"]),
+			String: *str,
+		})
+	}
+
+	return fs, nil
+}
+
+// Older LSJ files use numeric type ids instead of type names
+func lsjAttributeType(value interface{}) (resource.AttributeType, bool) {
+	switch v := value.(type) {
+	case string:
+		return resource.ParseAttributeType(v)
+	case json.Number:
+		return resource.ParseAttributeType(v.String())
+	}
+	return resource.AttrNone, false
+}
+
+// Converts a JSON value back to the text form parseAttributeValue understands
+func lsjString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	}
+	return ""
+}
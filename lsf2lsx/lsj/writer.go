@@ -0,0 +1,216 @@
+// Package lsj reads and writes the LSJ (JSON) format.
+package lsj
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// WriterOptions configures the LSJ output. The zero value (and nil) sorts everything for diffing.
+type WriterOptions struct {
+	// Order of regions, attributes and children, see resource.Order
+	Order resource.Order
+	// Node name -> attribute that same-named siblings are sorted by first, see resource.Sorter
+	SortKeys map[string]string
+}
+
+func WriteFile(filename string, res *resource.Resource, opts *WriterOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Write(file, res, opts)
+}
+
+/*
+Writes the LSJ (JSON) format used by Divine and the Script Extender:
+
+	{"save": {"header": {"version": "4.0.9.0"}, "regions": {"<region>": <node>}}}
+
+A node is an object where attributes map to {"type": ..., "value": ...} objects and children map to
+arrays of nodes. By default attribute and child names come out sorted together just like the LSX writer,
+and same-named siblings are sorted by their key and hash. With OrderFile the attributes are written first and
+then the children, each in the order they were read, which is how Divine lays out its LSJ files.
+*/
+func Write(w io.Writer, res *resource.Resource, opts *WriterOptions) error {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+
+	sorter := &resource.Sorter{Keys: opts.SortKeys}
+	regions := &lsjObject{}
+	for _, regionName := range res.RegionNamesInOrder(opts.Order) {
+		node, err := lsjNode(sorter, &res.Regions[regionName].Node, opts)
+		if err != nil {
+			return fmt.Errorf("region %q: %w", regionName, err)
+		}
+		regions.set(regionName, node)
+	}
+
+	version := fmt.Sprintf("%d.%d.%d.%d",
+		res.Metadata.MajorVersion,
+		res.Metadata.MinorVersion,
+		res.Metadata.Revision,
+		res.Metadata.BuildNumber)
+
+	save := map[string]interface{}{
+		"save": map[string]interface{}{
+			"header":  map[string]interface{}{"version": version},
+			"regions": regions,
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(save)
+}
+
+// Name of the string holding a node's key attribute, next to its attributes and children
+const lsjKeyName = "$key"
+
+func lsjNode(sorter *resource.Sorter, node *resource.Node, opts *WriterOptions) (json.RawMessage, error) {
+	values := make(map[string]json.RawMessage, len(node.Attributes)+len(node.Children)+1)
+	keys := make([]string, 0, len(node.Attributes)+len(node.Children)+1)
+
+	if node.KeyAttribute != "" {
+		value, err := marshalJSON(node.KeyAttribute)
+		if err != nil {
+			return nil, err
+		}
+		values[lsjKeyName] = value
+		keys = append(keys, lsjKeyName)
+	}
+
+	for _, attrName := range node.AttributeNamesInOrder(opts.Order) {
+		if attrName == lsjKeyName {
+			return nil, fmt.Errorf("node %q has an attribute named %q, which LSJ uses for the key attribute", node.Name, attrName)
+		}
+		attr := node.Attributes[attrName]
+		err := attr.Load()
+		if err != nil {
+			return nil, fmt.Errorf("node %q, attribute %q: %w", node.Name, attrName, err)
+		}
+		value, err := marshalJSON(lsjAttribute(attr))
+		if err != nil {
+			return nil, fmt.Errorf("node %q, attribute %q: %w", node.Name, attrName, err)
+		}
+		values[attrName] = value
+		keys = append(keys, attrName)
+	}
+
+	for _, childName := range node.ChildNamesInOrder(opts.Order) {
+		if _, exists := values[childName]; exists {
+			return nil, fmt.Errorf("node %q has an attribute and a child both named %q, which LSJ can't represent", node.Name, childName)
+		}
+
+		children := sorter.OrderSiblings(node.Children[childName], opts.Order)
+		childObjs := make([]json.RawMessage, len(children))
+		for i, child := range children {
+			childObj, err := lsjNode(sorter, child, opts)
+			if err != nil {
+				return nil, err
+			}
+			childObjs[i] = childObj
+		}
+		value, err := marshalJSON(childObjs)
+		if err != nil {
+			return nil, err
+		}
+		values[childName] = value
+		keys = append(keys, childName)
+	}
+
+	if opts.Order == resource.OrderSorted {
+		// "$key" sorts ahead of the names, which start with letters
+		sort.Strings(keys)
+	}
+
+	obj := &lsjObject{keys: keys, values: values}
+	return obj.MarshalJSON()
+}
+
+func lsjAttribute(attr *resource.NodeAttribute) map[string]interface{} {
+	obj := map[string]interface{}{
+		"type": attr.Type.String(),
+	}
+
+	switch attr.Type {
+	case resource.AttrTranslatedString:
+		ts := attr.Value.(*resource.TranslatedString)
+		obj["handle"] = ts.Handle
+		if ts.Value != "" {
+			obj["value"] = ts.Value
+		} else {
+			obj["version"] = ts.Version
+		}
+
+	case resource.AttrTranslatedFSString:
+		fs := attr.Value.(*resource.TranslatedFSString)
+		for key, value := range lsjTranslatedFSString(*fs) {
+			obj[key] = value
+		}
+
+	case resource.AttrBool:
+		obj["value"] = attr.Value.(bool)
+
+	default:
+		valueStr := strings.ReplaceAll(attr.ValueString(), "\x1f", "")
+		if isNumericAttributeType(attr.Type) && !isNonFinite(attr) {
+			// Keep the exact formatting the LSX writer uses
+			obj["value"] = json.Number(valueStr)
+		} else {
+			obj["value"] = valueStr
+		}
+	}
+
+	return obj
+}
+
+func lsjTranslatedFSString(fs resource.TranslatedFSString) map[string]interface{} {
+	args := make([]interface{}, len(fs.Arguments))
+	for i, arg := range fs.Arguments {
+		args[i] = map[string]interface{}{
+			"key":    arg.Key,
+			"value":  arg.Value,
+			"string": lsjTranslatedFSString(arg.String),
+		}
+	}
+
+	return map[string]interface{}{
+		"value":     fs.Value,
+		"handle":    fs.Handle,
+		"arguments": args,
+	}
+}
+
+// JSON numbers can't be NaN or infinite, so those floats are written as strings, which the reader parses too
+func isNonFinite(attr *resource.NodeAttribute) bool {
+	var value float64
+	switch v := attr.Value.(type) {
+	case float32:
+		value = float64(v)
+	case float64:
+		value = v
+	default:
+		return false
+	}
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
+
+func isNumericAttributeType(attrType resource.AttributeType) bool {
+	switch attrType {
+	case resource.AttrByte, resource.AttrShort, resource.AttrUShort, resource.AttrInt, resource.AttrUInt, resource.AttrFloat, resource.AttrDouble,
+		resource.AttrULongLong, resource.AttrLong, resource.AttrInt8, resource.AttrInt64:
+		return true
+	}
+	return false
+}
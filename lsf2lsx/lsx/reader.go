@@ -0,0 +1,209 @@
+package lsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// XML layout of an LSX V4 document, mirroring what writeRegions/writeNode/writeAttribute produce
+type lsxSave struct {
+	Version lsxVersion  `xml:"version"`
+	Regions []lsxRegion `xml:"region"`
+}
+
+type lsxVersion struct {
+	Major    string `xml:"major,attr"`
+	Minor    string `xml:"minor,attr"`
+	Revision string `xml:"revision,attr"`
+	Build    string `xml:"build,attr"`
+}
+
+type lsxRegion struct {
+	ID    string    `xml:"id,attr"`
+	Nodes []lsxNode `xml:"node"`
+}
+
+type lsxNode struct {
+	ID         string         `xml:"id,attr"`
+	Key        string         `xml:"key,attr"`
+	Attributes []lsxAttribute `xml:"attribute"`
+	Children   []lsxNode      `xml:"children>node"`
+}
+
+type lsxAttribute struct {
+	ID        string        `xml:"id,attr"`
+	Type      string        `xml:"type,attr"`
+	Value     string        `xml:"value,attr"`
+	Handle    string        `xml:"handle,attr"`
+	Version   string        `xml:"version,attr"`
+	Arguments []lsxArgument `xml:"arguments>argument"`
+}
+
+type lsxArgument struct {
+	Key    string    `xml:"key,attr"`
+	Value  string    `xml:"value,attr"`
+	String lsxString `xml:"string"`
+}
+
+type lsxString struct {
+	Value     string        `xml:"value,attr"`
+	Handle    string        `xml:"handle,attr"`
+	Arguments []lsxArgument `xml:"arguments>argument"`
+}
+
+// Wrapper for Read to handle file opening, also takes "archive.pak:path/in/archive" paths
+func ReadFile(filename string) (*resource.Resource, error) {
+	file, err := pak.OpenPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+func Read(r io.Reader) (*resource.Resource, error) {
+	save := &lsxSave{}
+	err := xml.NewDecoder(r).Decode(save)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LSX document: %w", err)
+	}
+
+	res := &resource.Resource{
+		Regions: make(map[string]*resource.Region),
+	}
+
+	err = readVersion(save.Version, res)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lsxRegion := range save.Regions {
+		if len(lsxRegion.Nodes) != 1 {
+			return nil, fmt.Errorf("region %q must contain exactly one root node, got %d", lsxRegion.ID, len(lsxRegion.Nodes))
+		}
+
+		region := &resource.Region{RegionName: lsxRegion.ID}
+		err = readNode(lsxRegion.Nodes[0], &region.Node)
+		if err != nil {
+			return nil, fmt.Errorf("region %q: %w", lsxRegion.ID, err)
+		}
+		res.AddRegion(region)
+	}
+
+	return res, nil
+}
+
+func readVersion(version lsxVersion, res *resource.Resource) error {
+	fields := []struct {
+		name  string
+		value string
+		dest  *uint32
+	}{
+		{"major", version.Major, &res.Metadata.MajorVersion},
+		{"minor", version.Minor, &res.Metadata.MinorVersion},
+		{"revision", version.Revision, &res.Metadata.Revision},
+		{"build", version.Build, &res.Metadata.BuildNumber},
+	}
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		val, err := strconv.ParseUint(field.value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %s version %q", field.name, field.value)
+		}
+		*field.dest = uint32(val)
+	}
+
+	return nil
+}
+
+func readNode(lsxNode lsxNode, node *resource.Node) error {
+	node.Name = lsxNode.ID
+	node.KeyAttribute = lsxNode.Key
+	node.Attributes = make(map[string]*resource.NodeAttribute)
+	node.Children = make(map[string][]*resource.Node)
+
+	for _, lsxAttr := range lsxNode.Attributes {
+		attr, err := readLSXAttribute(lsxAttr)
+		if err != nil {
+			return fmt.Errorf("node %q, attribute %q: %w", node.Name, lsxAttr.ID, err)
+		}
+		node.SetAttribute(lsxAttr.ID, attr)
+	}
+
+	for _, lsxChild := range lsxNode.Children {
+		child := &resource.Node{Parent: node}
+		err := readNode(lsxChild, child)
+		if err != nil {
+			return err
+		}
+		node.AppendChild(child)
+	}
+
+	return nil
+}
+
+func readLSXAttribute(lsxAttr lsxAttribute) (*resource.NodeAttribute, error) {
+	attrType, ok := resource.ParseAttributeType(lsxAttr.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute type %q", lsxAttr.Type)
+	}
+	attr := &resource.NodeAttribute{Type: attrType}
+
+	switch attrType {
+	case resource.AttrTranslatedString:
+		ts := &resource.TranslatedString{
+			Handle: lsxAttr.Handle,
+			Value:  lsxAttr.Value,
+		}
+		if lsxAttr.Version != "" {
+			version, err := strconv.ParseUint(lsxAttr.Version, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TranslatedString version %q", lsxAttr.Version)
+			}
+			ts.Version = uint16(version)
+		}
+		attr.Value = ts
+
+	case resource.AttrTranslatedFSString:
+		// The version isn't written to LSX, so it's always 0 here
+		attr.Value = &resource.TranslatedFSString{
+			Handle:    lsxAttr.Handle,
+			Value:     lsxAttr.Value,
+			Arguments: readTranslatedFSStringArguments(lsxAttr.Arguments),
+		}
+
+	default:
+		value, err := resource.ParseAttributeValue(attrType, lsxAttr.Value)
+		if err != nil {
+			return nil, err
+		}
+		attr.Value = value
+	}
+
+	return attr, nil
+}
+
+func readTranslatedFSStringArguments(lsxArgs []lsxArgument) []resource.TranslatedFSStringArgument {
+	args := make([]resource.TranslatedFSStringArgument, len(lsxArgs))
+	for i, lsxArg := range lsxArgs {
+		args[i] = resource.TranslatedFSStringArgument{
+			Key:   lsxArg.Key,
+			Value: lsxArg.Value,
+			String: resource.TranslatedFSString{
+				Handle:    lsxArg.String.Handle,
+				Value:     lsxArg.String.Value,
+				Arguments: readTranslatedFSStringArguments(lsxArg.String.Arguments),
+			},
+		}
+	}
+	return args
+}
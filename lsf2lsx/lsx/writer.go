@@ -0,0 +1,347 @@
+// Package lsx reads and writes the LSX (XML) format.
+package lsx
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// WriterOptions configures the LSX output. The zero value (and nil) sorts everything for diffing.
+type WriterOptions struct {
+	// Order of regions, attributes and children, see resource.Order
+	Order resource.Order
+	// Node name -> attribute that same-named siblings are sorted by first, see resource.Sorter
+	SortKeys map[string]string
+	// Handle -> text (see loca.Resource.Texts). Translated strings with a known handle get their text as a
+	// comment after the attribute, so diffs show what changed. The comments are ignored when reading.
+	Translations map[string]string
+}
+
+func WriteFile(filename string, res *resource.Resource, opts *WriterOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return Write(file, res, opts)
+}
+
+func Write(w io.Writer, res *resource.Resource, opts *WriterOptions) error {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+
+	_, err := w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>` + "\n"))
+	if err != nil {
+		return err
+	}
+
+	// Write root
+	err = encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "save"}})
+	if err != nil {
+		return err
+	}
+
+	err = writeVersion(encoder, res)
+	if err != nil {
+		return err
+	}
+
+	err = writeRegions(encoder, &resource.Sorter{Keys: opts.SortKeys}, res, opts)
+	if err != nil {
+		return err
+	}
+
+	// Close root
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "save"}})
+	if err != nil {
+		return err
+	}
+
+	err = encoder.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeVersion(encoder *xml.Encoder, res *resource.Resource) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "major"}, Value: strconv.FormatUint(uint64(res.Metadata.MajorVersion), 10)},
+		{Name: xml.Name{Local: "minor"}, Value: strconv.FormatUint(uint64(res.Metadata.MinorVersion), 10)},
+		{Name: xml.Name{Local: "revision"}, Value: strconv.FormatUint(uint64(res.Metadata.Revision), 10)},
+		{Name: xml.Name{Local: "build"}, Value: strconv.FormatUint(uint64(res.Metadata.BuildNumber), 10)},
+	}
+
+	err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "version"}, Attr: attrs})
+	if err != nil {
+		return err
+	}
+
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "version"}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeRegions(encoder *xml.Encoder, sorter *resource.Sorter, res *resource.Resource, opts *WriterOptions) error {
+	for _, regionName := range res.RegionNamesInOrder(opts.Order) {
+		region := res.Regions[regionName]
+		attrs := []xml.Attr{
+			{Name: xml.Name{Local: "id"}, Value: regionName},
+		}
+
+		err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "region"}, Attr: attrs})
+		if err != nil {
+			return err
+		}
+
+		// BG3 uses LSX V4 format (type names instead of IDs)
+		err = writeNode(encoder, sorter, &region.Node, opts)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "region"}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeNode(encoder *xml.Encoder, sorter *resource.Sorter, node *resource.Node, opts *WriterOptions) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "id"}, Value: node.Name},
+	}
+
+	if node.KeyAttribute != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "key"}, Value: node.KeyAttribute})
+	}
+
+	err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "node"}, Attr: attrs})
+	if err != nil {
+		return err
+	}
+
+	// By default we sort everything before writing to ensure the LSX is deterministic.
+
+	//// Attributes ////
+	for _, attrName := range node.AttributeNamesInOrder(opts.Order) {
+		err = writeAttribute(encoder, attrName, node.Attributes[attrName], opts.Translations)
+		if err != nil {
+			return err
+		}
+	}
+
+	//// Children ////
+	if len(node.Children) > 0 {
+		err = encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "children"}})
+		if err != nil {
+			return err
+		}
+
+		// Sort child node names alphabetically first
+		for _, childName := range node.ChildNamesInOrder(opts.Order) {
+			// Multiple children with the same name - sort by their key, then their hash
+			for _, child := range sorter.OrderSiblings(node.Children[childName], opts.Order) {
+				err = writeNode(encoder, sorter, child, opts)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "children"}})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "node"}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeAttribute(encoder *xml.Encoder, attrName string, attr *resource.NodeAttribute, translations map[string]string) error {
+	err := attr.Load()
+	if err != nil {
+		return err
+	}
+
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "id"}, Value: attrName},
+	}
+
+	// Type attribute (BG3 always uses V4 format with type names)
+	typeStr := attr.Type.String()
+	attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "type"}, Value: typeStr})
+
+	// Value attribute
+	switch attr.Type {
+	case resource.AttrTranslatedString:
+		ts := attr.Value.(*resource.TranslatedString)
+		if ts.Handle != "" {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "handle"}, Value: ts.Handle})
+		}
+		if ts.Value != "" {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "value"}, Value: ts.Value})
+		} else {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "version"}, Value: strconv.FormatUint(uint64(ts.Version), 10)})
+		}
+
+	case resource.AttrTranslatedFSString:
+		fs := attr.Value.(*resource.TranslatedFSString)
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "value"}, Value: fs.Value})
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "handle"}, Value: fs.Handle})
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "arguments"}, Value: strconv.Itoa(len(fs.Arguments))})
+
+	default:
+		valueStr := attr.ValueString()
+		// Remove bogus 0x1F characters
+		cleanValue := ""
+		for _, r := range valueStr {
+			if r != 0x1F {
+				cleanValue += string(r)
+			}
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "value"}, Value: cleanValue})
+	}
+
+	err = encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "attribute"}, Attr: attrs})
+	if err != nil {
+		return err
+	}
+
+	// Handle TranslatedFSString arguments
+	if attr.Type == resource.AttrTranslatedFSString {
+		fs := attr.Value.(*resource.TranslatedFSString)
+		if len(fs.Arguments) > 0 {
+			err = encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "arguments"}})
+			if err != nil {
+				return err
+			}
+
+			for _, arg := range fs.Arguments {
+				err = writeTranslatedFSStringArgument(encoder, arg, translations)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "arguments"}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "attribute"}})
+	if err != nil {
+		return err
+	}
+
+	switch value := attr.Value.(type) {
+	case *resource.TranslatedString:
+		return writeTranslation(encoder, value.Handle, translations)
+	case *resource.TranslatedFSString:
+		return writeTranslation(encoder, value.Handle, translations)
+	}
+	return nil
+}
+
+// Writes the text of a handle as a comment, on the same line as the element it follows
+func writeTranslation(encoder *xml.Encoder, handle string, translations map[string]string) error {
+	text, ok := translations[handle]
+	if !ok {
+		return nil
+	}
+
+	// Comments can't contain "--", and keeping them on one line keeps diffs readable
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "- -")
+	}
+	text = lineBreakEscaper.Replace(text)
+	return encoder.EncodeToken(xml.Comment(" " + text + " "))
+}
+
+var lineBreakEscaper = strings.NewReplacer("\r\n", "\\n", "\n", "\\n", "\r", "\\n")
+
+func writeTranslatedFSStringArgument(encoder *xml.Encoder, arg resource.TranslatedFSStringArgument, translations map[string]string) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "key"}, Value: arg.Key},
+		{Name: xml.Name{Local: "value"}, Value: arg.Value},
+	}
+
+	err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "argument"}, Attr: attrs})
+	if err != nil {
+		return err
+	}
+
+	// Write nested string
+	err = writeTranslatedFSString(encoder, arg.String, translations)
+	if err != nil {
+		return err
+	}
+
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "argument"}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeTranslatedFSString(encoder *xml.Encoder, fs resource.TranslatedFSString, translations map[string]string) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "value"}, Value: fs.Value},
+		{Name: xml.Name{Local: "handle"}, Value: fs.Handle},
+		{Name: xml.Name{Local: "arguments"}, Value: strconv.Itoa(len(fs.Arguments))},
+	}
+
+	err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "string"}, Attr: attrs})
+	if err != nil {
+		return err
+	}
+
+	if len(fs.Arguments) > 0 {
+		err = encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "arguments"}})
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range fs.Arguments {
+			err = writeTranslatedFSStringArgument(encoder, arg, translations)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "arguments"}})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "string"}})
+	if err != nil {
+		return err
+	}
+
+	return writeTranslation(encoder, fs.Handle, translations)
+}
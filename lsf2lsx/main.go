@@ -1,14 +1,64 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/loca"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsf"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsj"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/lsx"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/pak"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
 )
 
+// Subcommands, anything else is a conversion. Each returns the exit code.
+var commands = map[string]func(args []string) int{
+	"diff":   runDiff,
+	"merge":  runMerge,
+	"clean":  runClean,
+	"smudge": runSmudge,
+	"pack":   runPack,
+	"list":   runList,
+	"verify": runVerify,
+	"loca":   runLoca,
+	"export": runExport,
+	"import": runImport,
+	"batch":  runBatch,
+	"watch":  runWatch,
+}
+
+// Used for every LSF file read, batch conversions change it before starting their workers
+var lsfReaderOptions = lsf.DefaultReaderOptions
+
 func main() {
-	var inputFile = flag.String("i", "", "Input LSF file path")
-	var outputFile = flag.String("o", "", "Output LSX file path (optional, defaults to stdout)")
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	var inputFile = flag.String("i", "", "Input LSF, LSX or LSJ file path, - for stdin")
+	var outputFile = flag.String("o", "", "Output file path (optional, defaults to stdout)")
+	var outputFormat = flag.String("f", "lsx", "Output format: lsx, lsj or lsf")
+	var compressionMethod = flag.String("c", "", "LSF output compression: none, zlib, lz4 or zstd (defaults to the input LSF's, or lz4)")
+	var compressionLevel = flag.String("l", "default", "LSF output compression level: fast, default or max")
+	var outputOrder = flag.String("order", "sorted", "Output order: sorted (stable for diffs) or file (keeps the input's order, like Divine)")
+	var sortKeys = sortKeysFlag{}
+	flag.Var(sortKeys, "key", "Sort same-named nodes by an attribute first, as NodeName=Attribute (repeatable, e.g. -key GameObjects=MapKey)")
+	var locaFiles = filesFlag{}
+	flag.Var(&locaFiles, "loca", "Show the text of translated strings as comments in LSX output, from a .loca or localization XML file (repeatable)")
+	var regions = filesFlag{}
+	flag.Var(&regions, "region", "Only convert this region of LSF input (repeatable)")
+	var paths = filesFlag{}
+	flag.Var(&paths, "path", "Only convert the nodes on this path of LSF input, like Templates/GameObjects[MapKey=<guid>] (repeatable)")
 	flag.Parse()
 
 	// For git textconv, accept file path as positional argument
@@ -17,6 +67,11 @@ func main() {
 		*inputFile = args[0]
 	}
 
+	// Piped input without a file name is read from stdin
+	if *inputFile == "" && !isTerminal(os.Stdin) {
+		*inputFile = "-"
+	}
+
 	if *inputFile == "" {
 		fmt.Fprintf(os.Stderr, "Error: input file path is required\n")
 		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <input-file>\n", os.Args[0])
@@ -24,22 +79,296 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Read LSF file
-	resource, err := ReadLSF(*inputFile)
+	// Read LSF, LSX or LSJ file
+	lsfReaderOptions.Regions = regions
+	lsfReaderOptions.Paths = paths
+	res, lsfReader, err := readResource(*inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+		os.Exit(1)
+	}
+	if lsfReader == nil && (len(regions) > 0 || len(paths) > 0) {
+		fmt.Fprintf(os.Stderr, "Error: -region and -path only work with LSF input\n")
+		os.Exit(1)
+	}
+
+	// LSF to LSF conversions keep the original version and compression unless told otherwise
+	lsfOptions := lsf.DefaultWriterOptions
+	if lsfReader != nil {
+		lsfOptions.Version = lsfReader.Version()
+		lsfOptions.Compression = lsfReader.CompressionFlags()
+	}
+	levelSet := false
+	flag.Visit(func(f *flag.Flag) { levelSet = levelSet || f.Name == "l" })
+	if *compressionMethod != "" {
+		lsfOptions.Compression, err = parseCompressionFlags(*compressionMethod, *compressionLevel)
+	} else if levelSet {
+		// -l on its own changes the level of the kept or default method
+		method := lsfOptions.Compression.Method()
+		if method == compression.None {
+			err = fmt.Errorf("-l has no effect without compression, use -c to pick a method")
+		} else {
+			lsfOptions.Compression, err = parseCompressionFlags(method.String(), *compressionLevel)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	order, err := parseOrder(*outputOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	lsfOptions.Order = order
+	lsfOptions.SortKeys = sortKeys
+
+	translations, err := readTranslations(locaFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeResource, err := resourceWriter(*outputFormat, order, sortKeys, translations, &lsfOptions)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading LSF file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Write LSX to stdout or file
+	// Write to stdout or file
 	if *outputFile == "" {
 		// Write to stdout (for git textconv)
-		err = WriteLSXToWriter(os.Stdout, resource)
+		err = writeResource(os.Stdout, res)
 	} else {
-		err = WriteLSX(*outputFile, resource)
+		var file *os.File
+		file, err = os.Create(*outputFile)
+		if err == nil {
+			err = writeResource(file, res)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
 	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing LSX: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", strings.ToUpper(*outputFormat), err)
 		os.Exit(1)
 	}
 }
+
+// Translations are only shown in LSX, the other formats have nowhere to put them
+func resourceWriter(format string, order resource.Order, sortKeys map[string]string, translations map[string]string, lsfOptions *lsf.WriterOptions) (func(io.Writer, *resource.Resource) error, error) {
+	switch strings.ToLower(format) {
+	case "lsx":
+		return func(w io.Writer, res *resource.Resource) error {
+			return lsx.Write(w, res, &lsx.WriterOptions{Order: order, SortKeys: sortKeys, Translations: translations})
+		}, nil
+	case "lsj":
+		return func(w io.Writer, res *resource.Resource) error {
+			return lsj.Write(w, res, &lsj.WriterOptions{Order: order, SortKeys: sortKeys})
+		}, nil
+	case "lsf":
+		return func(w io.Writer, res *resource.Resource) error {
+			return lsf.Write(w, res, lsfOptions)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (expected lsx, lsj or lsf)", format)
+}
+
+// Collects repeated -key NodeName=Attribute flags
+type sortKeysFlag map[string]string
+
+func (f sortKeysFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for nodeName, attrName := range f {
+		pairs = append(pairs, nodeName+"="+attrName)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f sortKeysFlag) Set(value string) error {
+	nodeName, attrName, ok := strings.Cut(value, "=")
+	if !ok || nodeName == "" || attrName == "" {
+		return fmt.Errorf("expected NodeName=Attribute, got %q", value)
+	}
+	f[nodeName] = attrName
+	return nil
+}
+
+// Collects repeated file flags
+type filesFlag []string
+
+func (f *filesFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *filesFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Handle -> text from localization files, later files win. Returns nil without any files.
+func readTranslations(filenames []string) (map[string]string, error) {
+	if len(filenames) == 0 {
+		return nil, nil
+	}
+	translations := make(map[string]string)
+	for _, filename := range filenames {
+		res, err := loca.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", filename, err)
+		}
+		for handle, text := range res.Texts() {
+			translations[handle] = text
+		}
+	}
+	return translations, nil
+}
+
+func parseOrder(order string) (resource.Order, error) {
+	switch strings.ToLower(order) {
+	case "sorted":
+		return resource.OrderSorted, nil
+	case "file":
+		return resource.OrderFile, nil
+	}
+	return 0, fmt.Errorf("unknown order %q (expected sorted or file)", order)
+}
+
+func parseCompressionFlags(method string, level string) (compression.Flags, error) {
+	methods := map[string]compression.Method{
+		"none": compression.None,
+		"zlib": compression.Zlib,
+		"lz4":  compression.LZ4,
+		"zstd": compression.Zstd,
+	}
+	levels := map[string]uint8{
+		"fast":    compression.LevelFast,
+		"default": compression.LevelDefault,
+		"max":     compression.LevelMax,
+	}
+
+	compressionMethod, ok := methods[strings.ToLower(method)]
+	if !ok {
+		return 0, fmt.Errorf("unknown compression %q (expected none, zlib, lz4 or zstd)", method)
+	}
+	compressionLevel, ok := levels[strings.ToLower(level)]
+	if !ok {
+		return 0, fmt.Errorf("unknown compression level %q (expected fast, default or max)", level)
+	}
+	if compressionMethod == compression.None {
+		compressionLevel = 0
+	}
+	return compression.MakeFlags(compressionMethod, compressionLevel), nil
+}
+
+// The format is detected from the content rather than the extension, as git textconv hands us temp files.
+// The LSF reader is also returned for LSF input, so its settings can be carried over.
+func readResource(filename string) (*resource.Resource, *lsf.Reader, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return decodeResource(file, filename)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Opens a file, a file in a pak ("archive.pak:path/in/archive"), or stdin for "-"
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return pak.OpenPath(filename)
+}
+
+// Reads a resource in any of the formats from a stream, which doesn't need to be seekable. The name is only used for errors.
+func decodeResource(stream io.Reader, name string) (*resource.Resource, *lsf.Reader, error) {
+	// Files are passed on as they are, so the LSF reader can check the section sizes against the file size
+	input, header, err := peekHeader(stream, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res *resource.Resource
+	switch detectFormat(header) {
+	case "lsf":
+		reader := lsf.NewReaderWithOptions(input, &lsfReaderOptions)
+		res, err = reader.Read()
+		return res, reader, err
+	case "lsx":
+		res, err = lsx.Read(input)
+		return res, nil, err
+	case "lsj":
+		res, err = lsj.Read(input)
+		return res, nil, err
+	}
+	return nil, nil, fmt.Errorf("%s is not an LSF, LSX or LSJ file", name)
+}
+
+// Returns up to size bytes from the start of the stream, and a reader that still starts there. Seekable
+// streams are read and rewound, others are buffered.
+func peekHeader(stream io.Reader, size int) (io.Reader, []byte, error) {
+	if seeker, ok := stream.(io.ReadSeeker); ok {
+		// Pipes are *os.Files too, but fail to seek
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			header := make([]byte, size)
+			n, err := io.ReadFull(seeker, header)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, nil, err
+			}
+			_, err = seeker.Seek(start, io.SeekStart)
+			if err != nil {
+				return nil, nil, err
+			}
+			return seeker, header[:n], nil
+		}
+	}
+
+	buffered := bufio.NewReader(stream)
+	header, err := buffered.Peek(size)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	return buffered, header, nil
+}
+
+func detectFileFormat(filename string) (string, error) {
+	file, err := pak.OpenPath(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 64)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	format := detectFormat(header[:n])
+	if format == "" {
+		return "", fmt.Errorf("%s is not an LSF, LSX or LSJ file", filename)
+	}
+	return format, nil
+}
+
+func detectFormat(header []byte) string {
+	if bytes.HasPrefix(header, lsf.Signature) {
+		return "lsf"
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case bytes.HasPrefix(text, []byte("<")):
+		return "lsx"
+	case bytes.HasPrefix(text, []byte("{")):
+		return "lsj"
+	}
+	return ""
+}
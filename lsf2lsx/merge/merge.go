@@ -0,0 +1,239 @@
+// Package merge does three-way merges of resources, for use as a git merge driver.
+package merge
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/diff"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Conflict is a change made differently on both sides. The merged resource keeps our side of it.
+type Conflict struct {
+	Path string
+	// Empty for conflicts on whole nodes
+	Attribute string
+	Reason    string
+	// Values on each side, empty when absent
+	Base   string
+	Ours   string
+	Theirs string
+}
+
+func (c Conflict) String() string {
+	target := c.Path
+	if c.Attribute != "" {
+		target += " " + c.Attribute
+	}
+	if c.Base == "" && c.Ours == "" && c.Theirs == "" {
+		return fmt.Sprintf("CONFLICT %s: %s", target, c.Reason)
+	}
+	return fmt.Sprintf("CONFLICT %s: %s (base %q, ours %q, theirs %q)", target, c.Reason, c.Base, c.Ours, c.Theirs)
+}
+
+// Options configures how nodes are matched up
+type Options struct {
+	// Node name -> attribute identifying nodes of that name, on top of the nodes' own keys (see resource.Sorter)
+	Keys map[string]string
+}
+
+/*
+Resources merges the changes from base to theirs into ours. Ours is modified in place and becomes the
+result, so anything nobody touched keeps our order and layout.
+
+Nodes are matched between the versions like diff does (key attribute, GUID, contents, then position). For
+each attribute and node the usual three-way rules apply: if only one side changed it that change wins, if
+both made the same change it's taken once, and otherwise it's a conflict and our side is kept. Deleting a
+node on one side while the other side modified it is a conflict too. Nodes added on both sides are merged
+with each other if they have the same identity, otherwise both are kept.
+*/
+func Resources(base, ours, theirs *resource.Resource, opts *Options) []Conflict {
+	if opts == nil {
+		opts = &Options{}
+	}
+	m := &merger{sorter: &resource.Sorter{Keys: opts.Keys}}
+
+	if ours.Metadata == base.Metadata {
+		ours.Metadata = theirs.Metadata
+	}
+
+	for _, regionName := range unionNames(base.RegionNames(), ours.RegionNames(), theirs.RegionNames()) {
+		baseRegion, inBase := base.Regions[regionName]
+		ourRegion, inOurs := ours.Regions[regionName]
+		theirRegion, inTheirs := theirs.Regions[regionName]
+
+		switch {
+		case inOurs && inTheirs:
+			baseNode := &resource.Node{}
+			if inBase {
+				baseNode = &baseRegion.Node
+			}
+			m.mergeNode(regionName, baseNode, &ourRegion.Node, &theirRegion.Node)
+
+		case inTheirs && !inBase:
+			ours.AddRegion(theirRegion)
+
+		case inTheirs && resource.NodeHash(&baseRegion.Node) != resource.NodeHash(&theirRegion.Node):
+			m.conflict(Conflict{Path: regionName, Reason: "deleted in ours, modified in theirs"})
+
+		case inOurs && inBase:
+			if resource.NodeHash(&baseRegion.Node) == resource.NodeHash(&ourRegion.Node) {
+				delete(ours.Regions, regionName)
+			} else {
+				m.conflict(Conflict{Path: regionName, Reason: "modified in ours, deleted in theirs"})
+			}
+		}
+	}
+
+	return m.conflicts
+}
+
+type merger struct {
+	sorter    *resource.Sorter
+	conflicts []Conflict
+}
+
+func (m *merger) conflict(conflict Conflict) {
+	m.conflicts = append(m.conflicts, conflict)
+}
+
+func (m *merger) mergeNode(path string, base, ours, theirs *resource.Node) {
+	if ours.KeyAttribute == base.KeyAttribute {
+		ours.KeyAttribute = theirs.KeyAttribute
+	} else if theirs.KeyAttribute != base.KeyAttribute && theirs.KeyAttribute != ours.KeyAttribute {
+		m.conflict(Conflict{Path: path, Reason: "key attribute changed on both sides", Base: base.KeyAttribute, Ours: ours.KeyAttribute, Theirs: theirs.KeyAttribute})
+	}
+
+	for _, attrName := range unionNames(base.AttributeNames(), ours.AttributeNames(), theirs.AttributeNames()) {
+		m.mergeAttribute(path, attrName, base, ours, theirs)
+	}
+
+	for _, childName := range unionNames(base.ChildNames(), ours.ChildNames(), theirs.ChildNames()) {
+		m.mergeChildren(path, ours, base.Children[childName], ours.Children[childName], theirs.Children[childName])
+	}
+}
+
+func (m *merger) mergeAttribute(path string, attrName string, base, ours, theirs *resource.Node) {
+	baseAttr, ourAttr, theirAttr := base.Attributes[attrName], ours.Attributes[attrName], theirs.Attributes[attrName]
+	switch {
+	case sameAttribute(ourAttr, theirAttr), sameAttribute(baseAttr, theirAttr):
+		// Nothing to take from theirs
+
+	case sameAttribute(baseAttr, ourAttr):
+		if theirAttr == nil {
+			delete(ours.Attributes, attrName)
+		} else {
+			ours.SetAttribute(attrName, theirAttr)
+		}
+
+	default:
+		m.conflict(Conflict{
+			Path:      path,
+			Attribute: attrName,
+			Reason:    "changed on both sides",
+			Base:      attributeText(baseAttr),
+			Ours:      attributeText(ourAttr),
+			Theirs:    attributeText(theirAttr),
+		})
+	}
+}
+
+func (m *merger) mergeChildren(parentPath string, parent *resource.Node, base, ours, theirs []*resource.Node) {
+	base = m.sorter.Sort(base)
+	ours = m.sorter.Sort(ours)
+	theirs = m.sorter.Sort(theirs)
+
+	ourMatches := diff.Match(m.sorter, base, ours)
+	theirMatches := diff.Match(m.sorter, base, theirs)
+	ourMatched := make([]bool, len(ours))
+	theirMatched := make([]bool, len(theirs))
+
+	for i, baseNode := range base {
+		path := diff.NodePath(m.sorter, parentPath, baseNode, i, len(base))
+		j, k := ourMatches[i], theirMatches[i]
+		if j != -1 {
+			ourMatched[j] = true
+		}
+		if k != -1 {
+			theirMatched[k] = true
+		}
+
+		switch {
+		case j != -1 && k != -1:
+			m.mergeNode(path, baseNode, ours[j], theirs[k])
+
+		case j != -1:
+			// Deleted in theirs
+			if resource.NodeHash(baseNode) == resource.NodeHash(ours[j]) {
+				parent.RemoveChild(ours[j])
+			} else {
+				m.conflict(Conflict{Path: path, Reason: "modified in ours, deleted in theirs"})
+			}
+
+		case k != -1:
+			// Deleted in ours
+			if resource.NodeHash(baseNode) != resource.NodeHash(theirs[k]) {
+				m.conflict(Conflict{Path: path, Reason: "deleted in ours, modified in theirs"})
+			}
+		}
+	}
+
+	// Nodes added on both sides are only paired up if they're clearly the same node
+	ourAdded := unmatched(ours, ourMatched)
+	theirAdded := unmatched(theirs, theirMatched)
+	addedMatches := diff.MatchStrict(m.sorter, theirAdded, ourAdded)
+	for i, theirNode := range theirAdded {
+		j := addedMatches[i]
+		if j == -1 {
+			theirNode.Parent = parent
+			parent.AppendChild(theirNode)
+			continue
+		}
+		if resource.NodeHash(theirNode) != resource.NodeHash(ourAdded[j]) {
+			path := diff.NodePath(m.sorter, parentPath, ourAdded[j], j, len(ourAdded))
+			m.mergeNode(path, &resource.Node{}, ourAdded[j], theirNode)
+		}
+	}
+}
+
+func unmatched(nodes []*resource.Node, matched []bool) []*resource.Node {
+	result := make([]*resource.Node, 0)
+	for i, node := range nodes {
+		if !matched[i] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// Attributes are the same if both are missing, or they have the same type and value
+func sameAttribute(a, b *resource.NodeAttribute) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && diff.AttributeText(a) == diff.AttributeText(b)
+}
+
+func attributeText(attr *resource.NodeAttribute) string {
+	if attr == nil {
+		return ""
+	}
+	return diff.AttributeText(attr)
+}
+
+// Sorted union of name lists
+func unionNames(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, list := range lists {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,154 @@
+package pak
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// The archive is an fs.FS, so fs.WalkDir, fs.Glob, fs.ReadFile etc. work on it
+var (
+	_ fs.FS         = (*Archive)(nil)
+	_ fs.ReadFileFS = (*Archive)(nil)
+	_ fs.ReadDirFS  = (*Archive)(nil)
+	_ fs.StatFS     = (*Archive)(nil)
+)
+
+// Open opens a file or directory in the archive (fs.FS). Files are decompressed when opened.
+func (a *Archive) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if entry, ok := a.files[name]; ok {
+		data, err := a.ReadEntry(entry)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &file{info: fileInfo{entry: entry}, reader: bytes.NewReader(data)}, nil
+	}
+
+	if _, ok := a.dirs[name]; ok {
+		entries, _ := a.ReadDir(name)
+		return &dir{info: fileInfo{dir: path.Base(name)}, entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat describes a file or directory without decompressing anything (fs.StatFS)
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if entry, ok := a.files[name]; ok {
+		return fileInfo{entry: entry}, nil
+	}
+	if _, ok := a.dirs[name]; ok {
+		return fileInfo{dir: path.Base(name)}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists a directory, sorted by name (fs.ReadDirFS)
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	children, ok := a.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		info, err := a.Stat(path.Join(name, child))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+type fileInfo struct {
+	// Set for files
+	entry *Entry
+	// Name of the directory, for directories
+	dir string
+}
+
+func (fi fileInfo) Name() string {
+	if fi.entry != nil {
+		return path.Base(fi.entry.Name)
+	}
+	return fi.dir
+}
+
+func (fi fileInfo) Size() int64 {
+	if fi.entry != nil {
+		return fi.entry.Size()
+	}
+	return 0
+}
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.entry != nil {
+		return 0o444
+	}
+	return fs.ModeDir | 0o555
+}
+
+// Archives don't store modification times
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.entry == nil }
+func (fi fileInfo) Sys() interface{}   { return fi.entry }
+
+type file struct {
+	info   fileInfo
+	reader *bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Read(p []byte) (int, error) { return f.reader.Read(p) }
+func (f *file) Close() error               { return nil }
+
+// Files are fully decompressed in memory, so they can seek too
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *file) ReadAt(p []byte, offset int64) (int, error) {
+	return f.reader.ReadAt(p, offset)
+}
+
+type dir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.dir, Err: fs.ErrInvalid}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,52 @@
+package pak
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Separates the archive from the path inside it, as in Gustav.pak:Public/Shared/RootTemplates/_merged.lsf
+const pathSeparator = ".pak:"
+
+// SplitPath splits an "archive.pak:path/in/archive" path, ok is false for plain file paths
+func SplitPath(filename string) (archivePath string, name string, ok bool) {
+	index := strings.Index(strings.ToLower(filename), pathSeparator)
+	if index == -1 {
+		return "", "", false
+	}
+	split := index + len(pathSeparator) - 1
+	return filename[:split], filename[split+1:], true
+}
+
+// OpenPath opens a plain file, or a file inside an archive when given an "archive.pak:path/in/archive" path
+func OpenPath(filename string) (io.ReadSeekCloser, error) {
+	archivePath, name, ok := SplitPath(filename)
+	if !ok {
+		return os.Open(filename)
+	}
+
+	archive, err := Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	entry, ok := archive.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s not found in archive", archivePath, name)
+	}
+	data, err := archive.ReadEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,240 @@
+package pak
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+)
+
+// Open opens an LSPK v18 archive and reads its file list. The data itself is only read when files are opened.
+func Open(filename string) (*Archive, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		path:  filename,
+		parts: map[uint8]*os.File{0: file},
+	}
+	err = archive.readFileList(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return archive, nil
+}
+
+// Close closes the archive and any of its parts that were opened
+func (a *Archive) Close() error {
+	a.partsMu.Lock()
+	defer a.partsMu.Unlock()
+
+	var firstErr error
+	for _, part := range a.parts {
+		if err := part.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	a.parts = nil
+	return firstErr
+}
+
+// Entries returns the files in the archive, in the order of the file list
+func (a *Archive) Entries() []*Entry {
+	return a.entries
+}
+
+// Entry looks up a file by its path in the archive, backslashes and a leading slash are allowed
+func (a *Archive) Entry(name string) (*Entry, bool) {
+	entry, ok := a.files[cleanName(name)]
+	return entry, ok
+}
+
+func (a *Archive) readFileList(file *os.File) error {
+	signature := make([]byte, len(Signature))
+	_, err := io.ReadFull(file, signature)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	if !bytes.Equal(signature, Signature) {
+		return fmt.Errorf("not an LSPK archive")
+	}
+
+	err = binary.Read(file, binary.LittleEndian, &a.Header)
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if a.Header.Version != Version {
+		return fmt.Errorf("LSPK version %d is not supported (BG3 uses version %d)", a.Header.Version, Version)
+	}
+	if a.Header.Flags&FlagSolid != 0 {
+		return fmt.Errorf("solid archives are not supported")
+	}
+
+	// The file list is an LZ4 block of entries, preceded by the entry count and its compressed size
+	_, err = file.Seek(int64(a.Header.FileListOffset), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	var numFiles, compressedSize uint32
+	err = binary.Read(file, binary.LittleEndian, &numFiles)
+	if err != nil {
+		return fmt.Errorf("failed to read file list: %w", err)
+	}
+	err = binary.Read(file, binary.LittleEndian, &compressedSize)
+	if err != nil {
+		return fmt.Errorf("failed to read file list: %w", err)
+	}
+	if uint64(compressedSize)+8 > uint64(a.Header.FileListSize) {
+		return fmt.Errorf("file list size %d exceeds the size in the header (%d)", compressedSize, a.Header.FileListSize)
+	}
+
+	// LZ4 can't expand data more than ~255 times, anything beyond that is a corrupt count
+	if uint64(numFiles)*fileEntrySize > uint64(compressedSize)*255+fileEntrySize {
+		return fmt.Errorf("file list claims %d files in %d bytes", numFiles, compressedSize)
+	}
+
+	compressed := make([]byte, compressedSize)
+	_, err = io.ReadFull(file, compressed)
+	if err != nil {
+		return fmt.Errorf("failed to read file list: %w", err)
+	}
+	listData, err := compression.Decompress(compressed, int(numFiles)*fileEntrySize, compression.MakeFlags(compression.LZ4, compression.LevelDefault), false)
+	if err != nil {
+		return fmt.Errorf("failed to decompress file list: %w", err)
+	}
+
+	entries := make([]FileEntry18, numFiles)
+	err = binary.Read(bytes.NewReader(listData), binary.LittleEndian, entries)
+	if err != nil {
+		return fmt.Errorf("failed to read file list: %w", err)
+	}
+
+	a.entries = make([]*Entry, 0, numFiles)
+	a.files = make(map[string]*Entry, numFiles)
+	for _, fileEntry := range entries {
+		entry := &Entry{
+			Name:             cleanName(nullTerminated(fileEntry.Name[:])),
+			Offset:           uint64(fileEntry.OffsetInFile1) | uint64(fileEntry.OffsetInFile2)<<32,
+			ArchivePart:      fileEntry.ArchivePart,
+			Compression:      compression.Flags(fileEntry.Flags),
+			SizeOnDisk:       fileEntry.SizeOnDisk,
+			UncompressedSize: fileEntry.UncompressedSize,
+		}
+		a.entries = append(a.entries, entry)
+		a.files[entry.Name] = entry
+	}
+	a.buildDirs()
+
+	return nil
+}
+
+// Directories aren't stored, so they're derived from the file paths for ReadDir
+func (a *Archive) buildDirs() {
+	a.dirs = map[string][]string{".": {}}
+	for _, entry := range a.entries {
+		name := entry.Name
+		for name != "." {
+			dir := path.Dir(name)
+			children, seen := a.dirs[dir]
+			a.dirs[dir] = append(children, path.Base(name))
+			if seen {
+				break
+			}
+			name = dir
+		}
+	}
+
+	for dir, children := range a.dirs {
+		sort.Strings(children)
+		a.dirs[dir] = uniqueSorted(children)
+	}
+}
+
+// ReadFile returns the decompressed contents of a file in the archive (fs.ReadFileFS)
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := a.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return a.ReadEntry(entry)
+}
+
+// ReadEntry returns the decompressed contents of an entry
+func (a *Archive) ReadEntry(entry *Entry) ([]byte, error) {
+	part, err := a.part(entry.ArchivePart)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := make([]byte, entry.SizeOnDisk)
+	_, err = part.ReadAt(compressed, int64(entry.Offset))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read %s: %w", a.path, entry.Name, err)
+	}
+
+	data, err := compression.Decompress(compressed, int(entry.Size()), entry.Compression, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decompress %s: %w", a.path, entry.Name, err)
+	}
+	return data, nil
+}
+
+// Large archives are split into Name.pak, Name_1.pak, Name_2.pak, ...
+func (a *Archive) part(index uint8) (*os.File, error) {
+	a.partsMu.Lock()
+	defer a.partsMu.Unlock()
+
+	if a.parts == nil {
+		return nil, fmt.Errorf("%s: archive is closed", a.path)
+	}
+	if part, ok := a.parts[index]; ok {
+		return part, nil
+	}
+
+	ext := filepath.Ext(a.path)
+	partPath := fmt.Sprintf("%s_%d%s", strings.TrimSuffix(a.path, ext), index, ext)
+	part, err := os.Open(partPath)
+	if err != nil {
+		return nil, err
+	}
+	a.parts[index] = part
+	return part, nil
+}
+
+func nullTerminated(name []byte) string {
+	if end := bytes.IndexByte(name, 0); end != -1 {
+		name = name[:end]
+	}
+	return string(name)
+}
+
+// Paths are stored with forward slashes, but be lenient with whatever we're given
+func cleanName(name string) string {
+	name = strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/")
+	return path.Clean(name)
+}
+
+func uniqueSorted(names []string) []string {
+	result := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+// Package pak reads the LSPK (.pak) archives BG3 ships its data in.
+package pak
+
+import (
+	"os"
+	"sync"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+)
+
+// LSPK file signature
+var Signature = []byte{'L', 'S', 'P', 'K'}
+
+// LSPK version used by BG3
+const Version = 18
+
+// Package flags
+const (
+	FlagAllowMemoryMapping = 0x02
+	FlagSolid              = 0x04
+	FlagPreload            = 0x08
+)
+
+// Header follows the signature at the start of the archive (LSPKHeader16 in lslib)
+type Header struct {
+	Version        uint32
+	FileListOffset uint64
+	FileListSize   uint32
+	Flags          uint8
+	Priority       uint8
+	Md5            [16]byte
+	NumParts       uint16
+}
+
+// FileEntry18 is a file list entry, the file list itself is a compressed array of these
+type FileEntry18 struct {
+	Name             [256]byte
+	OffsetInFile1    uint32
+	OffsetInFile2    uint16
+	ArchivePart      uint8
+	Flags            uint8
+	SizeOnDisk       uint32
+	UncompressedSize uint32
+}
+
+// Size of FileEntry18 on disk
+const fileEntrySize = 272
+
+// Entry is a file stored in the archive
+type Entry struct {
+	// Path inside the archive, always with forward slashes
+	Name string
+	// Offset of the (compressed) data in its archive part
+	Offset uint64
+	// 0 is the main .pak, N is the _N.pak next to it
+	ArchivePart      uint8
+	Compression      compression.Flags
+	SizeOnDisk       uint32
+	UncompressedSize uint32
+}
+
+// Size returns the size of the file once decompressed
+func (e *Entry) Size() int64 {
+	if e.Compression.Method() == compression.None {
+		return int64(e.SizeOnDisk)
+	}
+	return int64(e.UncompressedSize)
+}
+
+// Archive is an open .pak, its files can be read directly or through the fs.FS interface
+type Archive struct {
+	Header  Header
+	path    string
+	entries []*Entry
+	files   map[string]*Entry
+	// Directory -> names of the files and directories directly in it
+	dirs    map[string][]string
+	partsMu sync.Mutex
+	parts   map[uint8]*os.File
+}
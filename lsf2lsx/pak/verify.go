@@ -0,0 +1,78 @@
+package pak
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+/*
+Verify checks that the file list is consistent with the archive and that every file decompresses to its
+recorded size. It returns every problem found rather than stopping at the first.
+
+Files must lie between the header and the file list (in the main part) or inside their part, must not
+overlap and must have unique names.
+*/
+func (a *Archive) Verify() []error {
+	problems := make([]error, 0)
+	if len(a.files) != len(a.entries) {
+		seen := make(map[string]bool, len(a.entries))
+		for _, entry := range a.entries {
+			if seen[entry.Name] {
+				problems = append(problems, fmt.Errorf("%s: listed more than once", entry.Name))
+			}
+			seen[entry.Name] = true
+		}
+	}
+
+	dataStart := uint64(len(Signature) + binary.Size(Header{}))
+	byPosition := make([]*Entry, len(a.entries))
+	copy(byPosition, a.entries)
+	sort.SliceStable(byPosition, func(i, j int) bool {
+		if byPosition[i].ArchivePart != byPosition[j].ArchivePart {
+			return byPosition[i].ArchivePart < byPosition[j].ArchivePart
+		}
+		return byPosition[i].Offset < byPosition[j].Offset
+	})
+
+	var previous *Entry
+	for _, entry := range byPosition {
+		end := entry.Offset + uint64(entry.SizeOnDisk)
+		partSize, err := a.partSize(entry.ArchivePart)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Errorf("%s: %w", entry.Name, err))
+			continue
+		case entry.ArchivePart == 0 && (entry.Offset < dataStart || end > a.Header.FileListOffset):
+			problems = append(problems, fmt.Errorf("%s: data at %d-%d overlaps the header or file list", entry.Name, entry.Offset, end))
+			continue
+		case end > partSize:
+			problems = append(problems, fmt.Errorf("%s: data at %d-%d is past the end of the archive (%d bytes)", entry.Name, entry.Offset, end, partSize))
+			continue
+		}
+
+		if previous != nil && previous.ArchivePart == entry.ArchivePart && previous.Offset+uint64(previous.SizeOnDisk) > entry.Offset {
+			problems = append(problems, fmt.Errorf("%s: data overlaps %s", entry.Name, previous.Name))
+		}
+		previous = entry
+
+		_, err = a.ReadEntry(entry)
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	return problems
+}
+
+func (a *Archive) partSize(index uint8) (uint64, error) {
+	part, err := a.part(index)
+	if err != nil {
+		return 0, err
+	}
+	info, err := part.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.Size()), nil
+}
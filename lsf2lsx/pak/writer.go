@@ -0,0 +1,186 @@
+package pak
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/compression"
+)
+
+// Longest path that fits in a file entry, with its null terminator
+const maxNameLength = 255
+
+// File offsets are split over 48 bits in the file entries
+const maxOffset = 1<<48 - 1
+
+// WriterOptions configures the archive
+type WriterOptions struct {
+	// Compression of the files. The zero value stores them uncompressed.
+	Compression compression.Flags
+	// Load order priority, mods normally use 0
+	Priority uint8
+	// Package flags (FlagPreload, ...)
+	Flags uint8
+}
+
+// DefaultWriterOptions are used when no options are given
+var DefaultWriterOptions = WriterOptions{
+	Compression: compression.MakeFlags(compression.LZ4, compression.LevelDefault),
+}
+
+/*
+Writer writes an LSPK v18 archive in a single part:
+
+	"LSPK" | Header | file data ... | file count | file list size | LZ4 compressed file list
+
+The header is written as a placeholder first and filled in by Close, once the file list offset is known.
+*/
+type Writer struct {
+	stream  io.WriteSeeker
+	file    *os.File
+	opts    WriterOptions
+	offset  uint64
+	entries []FileEntry18
+	names   map[string]bool
+}
+
+// Create creates an archive file, Close must be called to finish it
+func Create(filename string, opts *WriterOptions) (*Writer, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewWriter(file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.file = file
+	return writer, nil
+}
+
+func NewWriter(stream io.WriteSeeker, opts *WriterOptions) (*Writer, error) {
+	if opts == nil {
+		opts = &DefaultWriterOptions
+	}
+
+	w := &Writer{
+		stream: stream,
+		opts:   *opts,
+		names:  make(map[string]bool),
+	}
+
+	// Placeholder, rewritten by Close
+	err := w.writeHeader(Header{})
+	if err != nil {
+		return nil, err
+	}
+	w.offset = uint64(len(Signature)) + uint64(binary.Size(Header{}))
+	return w, nil
+}
+
+// WriteFile compresses and adds a file, name is its path in the archive
+func (w *Writer) WriteFile(name string, data []byte) error {
+	name = cleanName(name)
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%s: path is longer than %d bytes", name, maxNameLength)
+	}
+	if w.names[name] {
+		return fmt.Errorf("%s: file is already in the archive", name)
+	}
+	if w.offset > maxOffset {
+		return fmt.Errorf("%s: archive is too large", name)
+	}
+
+	compressed, err := compression.Compress(data, w.opts.Compression, false)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	_, err = w.stream.Write(compressed)
+	if err != nil {
+		return err
+	}
+
+	entry := FileEntry18{
+		OffsetInFile1: uint32(w.offset),
+		OffsetInFile2: uint16(w.offset >> 32),
+		Flags:         uint8(w.opts.Compression),
+		SizeOnDisk:    uint32(len(compressed)),
+	}
+	// Like lslib, uncompressed files have no uncompressed size
+	if w.opts.Compression.Method() != compression.None {
+		entry.UncompressedSize = uint32(len(data))
+	}
+	copy(entry.Name[:], name)
+
+	w.entries = append(w.entries, entry)
+	w.names[name] = true
+	w.offset += uint64(len(compressed))
+	return nil
+}
+
+// Close writes the file list and the header, and closes the file if the archive was made with Create
+func (w *Writer) Close() error {
+	err := w.finish()
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (w *Writer) finish() error {
+	var list bytes.Buffer
+	err := binary.Write(&list, binary.LittleEndian, w.entries)
+	if err != nil {
+		return err
+	}
+	compressed, err := compression.Compress(list.Bytes(), compression.MakeFlags(compression.LZ4, compression.LevelMax), false)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w.stream, binary.LittleEndian, uint32(len(w.entries)))
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w.stream, binary.LittleEndian, uint32(len(compressed)))
+	if err != nil {
+		return err
+	}
+	_, err = w.stream.Write(compressed)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.stream.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	err = w.writeHeader(Header{
+		Version:        Version,
+		FileListOffset: w.offset,
+		FileListSize:   uint32(8 + len(compressed)),
+		Flags:          w.opts.Flags,
+		Priority:       w.opts.Priority,
+		NumParts:       1,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.stream.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (w *Writer) writeHeader(header Header) error {
+	_, err := w.stream.Write(Signature)
+	if err != nil {
+		return err
+	}
+	return binary.Write(w.stream, binary.LittleEndian, &header)
+}
@@ -0,0 +1,349 @@
+package resource
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LSX V4 type names
+var typeNames = map[AttributeType]string{
+	AttrByte:               "uint8",
+	AttrShort:              "int16",
+	AttrUShort:             "uint16",
+	AttrInt:                "int32",
+	AttrUInt:               "uint32",
+	AttrFloat:              "float",
+	AttrDouble:             "double",
+	AttrIVec2:              "ivec2",
+	AttrIVec3:              "ivec3",
+	AttrIVec4:              "ivec4",
+	AttrVec2:               "fvec2",
+	AttrVec3:               "fvec3",
+	AttrVec4:               "fvec4",
+	AttrMat2:               "mat2x2",
+	AttrMat3:               "mat3x3",
+	AttrMat3x4:             "mat3x4",
+	AttrMat4x3:             "mat4x3",
+	AttrMat4:               "mat4x4",
+	AttrBool:               "bool",
+	AttrString:             "string",
+	AttrPath:               "path",
+	AttrFixedString:        "FixedString",
+	AttrLSString:           "LSString",
+	AttrULongLong:          "uint64",
+	AttrScratchBuffer:      "ScratchBuffer",
+	AttrLong:               "old_int64",
+	AttrInt8:               "int8",
+	AttrTranslatedString:   "TranslatedString",
+	AttrWString:            "WString",
+	AttrLSWString:          "LSWString",
+	AttrUUID:               "guid",
+	AttrInt64:              "int64",
+	AttrTranslatedFSString: "TranslatedFSString",
+}
+
+// String returns the LSX V4 type name
+func (attrType AttributeType) String() string {
+	if str, ok := typeNames[attrType]; ok {
+		return str
+	}
+	return "None"
+}
+
+// ParseAttributeType accepts both V4 type names and the numeric type ids used by older LSX versions
+func ParseAttributeType(typeStr string) (AttributeType, bool) {
+	for attrType, name := range typeNames {
+		if name == typeStr {
+			return attrType, true
+		}
+	}
+	if typeStr == "None" {
+		return AttrNone, true
+	}
+
+	typeId, err := strconv.ParseUint(typeStr, 10, 32)
+	if err != nil || AttributeType(typeId) > AttrMax {
+		return AttrNone, false
+	}
+	return AttributeType(typeId), true
+}
+
+// ValueString formats the value the way LSX and LSJ files store it. Lazy attributes are loaded first, those
+// that fail to decode come out empty (Load returns the error).
+func (attr *NodeAttribute) ValueString() string {
+	attr.Load()
+	switch v := attr.Value.(type) {
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case string:
+		return v
+	case []byte:
+		// Check if this is a UUID (16 bytes) or ScratchBuffer
+		if attr.Type == AttrUUID && len(v) == 16 {
+			// BG3 always byte-swaps GUIDs
+			return FormatUUID(v, true)
+		}
+		// ScratchBuffer - format as hex
+		return fmt.Sprintf("%x", v)
+	case [2]int32:
+		return fmt.Sprintf("%d %d", v[0], v[1])
+	case [3]int32:
+		return fmt.Sprintf("%d %d %d", v[0], v[1], v[2])
+	case [4]int32:
+		return fmt.Sprintf("%d %d %d %d", v[0], v[1], v[2], v[3])
+	case [2]float32:
+		return fmt.Sprintf("%g %g", v[0], v[1])
+	case [3]float32:
+		return fmt.Sprintf("%g %g %g", v[0], v[1], v[2])
+	case [4]float32:
+		return fmt.Sprintf("%g %g %g %g", v[0], v[1], v[2], v[3])
+	case []float32:
+		// For matrices
+		result := ""
+		for i, f := range v {
+			if i > 0 {
+				result += " "
+			}
+			result += strconv.FormatFloat(float64(f), 'g', -1, 32)
+		}
+		return result
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ParseAttributeValue is the reverse of ValueString
+func ParseAttributeValue(attrType AttributeType, valueStr string) (interface{}, error) {
+	var value interface{}
+	var err error
+
+	switch attrType {
+	case AttrNone:
+		return nil, nil
+	case AttrByte:
+		var val uint64
+		val, err = strconv.ParseUint(valueStr, 10, 8)
+		value = uint8(val)
+	case AttrShort:
+		var val int64
+		val, err = strconv.ParseInt(valueStr, 10, 16)
+		value = int16(val)
+	case AttrUShort:
+		var val uint64
+		val, err = strconv.ParseUint(valueStr, 10, 16)
+		value = uint16(val)
+	case AttrInt:
+		var val int64
+		val, err = strconv.ParseInt(valueStr, 10, 32)
+		value = int32(val)
+	case AttrUInt:
+		var val uint64
+		val, err = strconv.ParseUint(valueStr, 10, 32)
+		value = uint32(val)
+	case AttrFloat:
+		var val float64
+		val, err = strconv.ParseFloat(valueStr, 32)
+		value = float32(val)
+	case AttrDouble:
+		value, err = strconv.ParseFloat(valueStr, 64)
+	case AttrBool:
+		value, err = parseBool(valueStr)
+	case AttrString, AttrPath, AttrFixedString, AttrLSString, AttrWString, AttrLSWString:
+		value = valueStr
+	case AttrULongLong:
+		value, err = strconv.ParseUint(valueStr, 10, 64)
+	case AttrLong, AttrInt64:
+		value, err = strconv.ParseInt(valueStr, 10, 64)
+	case AttrInt8:
+		var val int64
+		val, err = strconv.ParseInt(valueStr, 10, 8)
+		value = int8(val)
+	case AttrIVec2:
+		var vals []int32
+		vals, err = parseInt32s(valueStr, 2)
+		if err == nil {
+			value = [2]int32(vals)
+		}
+	case AttrIVec3:
+		var vals []int32
+		vals, err = parseInt32s(valueStr, 3)
+		if err == nil {
+			value = [3]int32(vals)
+		}
+	case AttrIVec4:
+		var vals []int32
+		vals, err = parseInt32s(valueStr, 4)
+		if err == nil {
+			value = [4]int32(vals)
+		}
+	case AttrVec2:
+		var vals []float32
+		vals, err = parseFloat32s(valueStr, 2)
+		if err == nil {
+			value = [2]float32(vals)
+		}
+	case AttrVec3:
+		var vals []float32
+		vals, err = parseFloat32s(valueStr, 3)
+		if err == nil {
+			value = [3]float32(vals)
+		}
+	case AttrVec4:
+		var vals []float32
+		vals, err = parseFloat32s(valueStr, 4)
+		if err == nil {
+			value = [4]float32(vals)
+		}
+	case AttrMat2:
+		value, err = parseFloat32s(valueStr, 2*2)
+	case AttrMat3:
+		value, err = parseFloat32s(valueStr, 3*3)
+	case AttrMat3x4:
+		value, err = parseFloat32s(valueStr, 3*4)
+	case AttrMat4x3:
+		value, err = parseFloat32s(valueStr, 4*3)
+	case AttrMat4:
+		value, err = parseFloat32s(valueStr, 4*4)
+	case AttrUUID:
+		value, err = ParseUUID(valueStr, true)
+	case AttrScratchBuffer:
+		value, err = hex.DecodeString(valueStr)
+	default:
+		return nil, fmt.Errorf("unsupported attribute type %d", attrType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q: %w", attrType, valueStr, err)
+	}
+	return value, nil
+}
+
+func parseBool(valueStr string) (bool, error) {
+	switch strings.ToLower(valueStr) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected True or False")
+}
+
+func parseInt32s(valueStr string, count int) ([]int32, error) {
+	fields := strings.Fields(valueStr)
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d components, got %d", count, len(fields))
+	}
+
+	vals := make([]int32, count)
+	for i, field := range fields {
+		val, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = int32(val)
+	}
+	return vals, nil
+}
+
+func parseFloat32s(valueStr string, count int) ([]float32, error) {
+	fields := strings.Fields(valueStr)
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d components, got %d", count, len(fields))
+	}
+
+	vals := make([]float32, count)
+	for i, field := range fields {
+		val, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = float32(val)
+	}
+	return vals, nil
+}
+
+// ByteSwapUUID swaps the last 8 bytes in pairs, which BG3 does for every GUID
+func ByteSwapUUID(uuid []byte) []byte {
+	if len(uuid) != 16 {
+		return uuid
+	}
+	result := make([]byte, 16)
+	copy(result, uuid)
+	// Swap bytes 8-15 in pairs (indices 8-9, 10-11, 12-13, 14-15)
+	for i := 8; i < 16; i += 2 {
+		result[i], result[i+1] = result[i+1], result[i]
+	}
+	return result
+}
+
+// FormatUUID formats 16 raw bytes as xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func FormatUUID(uuid []byte, byteSwap bool) string {
+	if len(uuid) != 16 {
+		return fmt.Sprintf("%x", uuid)
+	}
+
+	bytes := uuid
+	if byteSwap {
+		bytes = ByteSwapUUID(uuid)
+	}
+
+	// Format as GUID: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+	return fmt.Sprintf("%02x%02x%02x%02x-%02x%02x-%02x%02x-%02x%02x-%02x%02x%02x%02x%02x%02x",
+		bytes[3], bytes[2], bytes[1], bytes[0], // First 4 bytes (little-endian)
+		bytes[5], bytes[4], // Next 2 bytes
+		bytes[7], bytes[6], // Next 2 bytes
+		bytes[8], bytes[9], // Next 2 bytes
+		bytes[10], bytes[11], bytes[12], bytes[13], bytes[14], bytes[15]) // Last 6 bytes
+}
+
+// ParseUUID is the reverse of FormatUUID
+func ParseUUID(uuidStr string, byteSwap bool) ([]byte, error) {
+	hexStr := strings.ReplaceAll(uuidStr, "-", "")
+	if len(hexStr) != 32 || len(uuidStr) != 36 {
+		return nil, fmt.Errorf("expected a GUID in the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	}
+
+	parsed, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// First 8 bytes are little-endian fields, the last 8 are stored as-is
+	uuid := []byte{
+		parsed[3], parsed[2], parsed[1], parsed[0],
+		parsed[5], parsed[4],
+		parsed[7], parsed[6],
+		parsed[8], parsed[9], parsed[10], parsed[11], parsed[12], parsed[13], parsed[14], parsed[15],
+	}
+
+	if byteSwap {
+		// Swapping is its own inverse
+		uuid = ByteSwapUUID(uuid)
+	}
+	return uuid, nil
+}
@@ -0,0 +1,56 @@
+package resource
+
+import (
+	"encoding/hex"
+	"regexp"
+)
+
+// Attribute names that usually hold a node's GUID, checked before any other GUID-like attribute
+var identityAttributeNames = []string{"UUID", "MapKey", "GUID", "Guid", "ID", "Id"}
+
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+/*
+Identity returns the attribute that tells a node apart from its same-named siblings, and its value.
+
+The key attribute (see KeyValue) wins if the node has one. Otherwise GUIDs are the next best thing, most
+nodes that exist more than once carry one (UUID, MapKey, ...). Either a guid typed attribute or a string
+holding a GUID counts. Nodes with neither have no identity and can only be matched by their contents.
+*/
+func (s *Sorter) Identity(node *Node) (string, string, bool) {
+	if value, ok := s.KeyValue(node); ok {
+		return s.keyName(node), value, true
+	}
+
+	for _, attrName := range identityAttributeNames {
+		if attr, ok := node.Attributes[attrName]; ok && isGUID(attr) {
+			return attrName, attr.ValueString(), true
+		}
+	}
+	for _, attrName := range node.AttributeNames() {
+		if attr := node.Attributes[attrName]; isGUID(attr) {
+			return attrName, attr.ValueString(), true
+		}
+	}
+
+	return "", "", false
+}
+
+func isGUID(attr *NodeAttribute) bool {
+	switch attr.Type {
+	case AttrUUID:
+		return true
+	case AttrString, AttrFixedString, AttrLSString, AttrWString, AttrLSWString:
+		attr.Load()
+		value, _ := attr.Value.(string)
+		return guidPattern.MatchString(value)
+	}
+	return false
+}
+
+// NodeHash returns a string that's the same for nodes with the same contents (see Sorter.Digest). Nodes can
+// change between calls, nothing is cached.
+func NodeHash(node *Node) string {
+	digest := (&Sorter{}).Digest(node)
+	return hex.EncodeToString(digest[:])
+}
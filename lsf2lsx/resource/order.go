@@ -0,0 +1,78 @@
+package resource
+
+/*
+Order picks how writers lay out regions, attributes and children.
+
+OrderSorted sorts everything (names alphabetically, same-named siblings by their hash) so the output only
+changes when the data does, which is what we want for diffs. OrderFile keeps the order the resource was
+read in, which is what Divine/LSLib writes: attributes in the order of the NextAttributeIndex chain, and
+children grouped by name, with the groups in order of first appearance and siblings in node index order.
+
+Names added straight to the maps without going through AddRegion/AppendChild/SetAttribute have no recorded
+position, OrderFile puts them after the recorded ones, sorted.
+*/
+type Order int
+
+const (
+	OrderSorted Order = iota
+	OrderFile
+)
+
+// RegionNamesInOrder returns the region names in the given order
+func (r *Resource) RegionNamesInOrder(order Order) []string {
+	if order == OrderFile {
+		return recordedOrder(r.RegionOrder, r.RegionNames(), func(name string) bool {
+			_, exists := r.Regions[name]
+			return exists
+		})
+	}
+	return r.RegionNames()
+}
+
+// AttributeNamesInOrder returns the attribute names in the given order
+func (n *Node) AttributeNamesInOrder(order Order) []string {
+	if order == OrderFile {
+		return recordedOrder(n.AttributeOrder, n.AttributeNames(), func(name string) bool {
+			_, exists := n.Attributes[name]
+			return exists
+		})
+	}
+	return n.AttributeNames()
+}
+
+// ChildNamesInOrder returns the child node names in the given order
+func (n *Node) ChildNamesInOrder(order Order) []string {
+	if order == OrderFile {
+		return recordedOrder(n.ChildOrder, n.ChildNames(), func(name string) bool {
+			return len(n.Children[name]) > 0
+		})
+	}
+	return n.ChildNames()
+}
+
+// OrderSiblings returns same-named sibling nodes in the given order, see Sorter.OrderSiblings
+func OrderSiblings(nodes []*Node, order Order) []*Node {
+	return (&Sorter{}).OrderSiblings(nodes, order)
+}
+
+// Recorded names first (skipping any that were since removed), then the unrecorded ones in sorted order
+func recordedOrder(recorded []string, sorted []string, exists func(string) bool) []string {
+	names := make([]string, 0, len(sorted))
+	seen := make(map[string]bool, len(sorted))
+	for _, name := range recorded {
+		if !seen[name] && exists(name) {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	if len(names) == len(sorted) {
+		return names
+	}
+
+	for _, name := range sorted {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
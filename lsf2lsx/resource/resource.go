@@ -0,0 +1,218 @@
+// Package resource holds the in-memory tree shared by every format: LSF, LSX and LSJ files are all read into
+// and written from a Resource.
+package resource
+
+// LSMetadata contains version information
+type LSMetadata struct {
+	Timestamp    uint64
+	MajorVersion uint32
+	MinorVersion uint32
+	Revision     uint32
+	BuildNumber  uint32
+}
+
+// Resource is the root structure containing regions
+type Resource struct {
+	Metadata LSMetadata
+	Regions  map[string]*Region
+	// Region names in the order they were read, see Order
+	RegionOrder []string
+}
+
+// AddRegion adds a region, remembering the order regions were added in
+func (r *Resource) AddRegion(region *Region) {
+	if r.Regions == nil {
+		r.Regions = make(map[string]*Region)
+	}
+	if _, exists := r.Regions[region.RegionName]; !exists {
+		r.RegionOrder = append(r.RegionOrder, region.RegionName)
+	}
+	r.Regions[region.RegionName] = region
+}
+
+// Region is a top-level container (root node)
+type Region struct {
+	Node
+	RegionName string
+}
+
+// Node represents a node in the resource tree
+type Node struct {
+	Name         string
+	Parent       *Node
+	Attributes   map[string]*NodeAttribute
+	Children     map[string][]*Node
+	KeyAttribute string
+	// Attribute and child names in the order they were first read, see Order
+	AttributeOrder []string
+	ChildOrder     []string
+}
+
+// AppendChild adds a child node
+func (n *Node) AppendChild(child *Node) {
+	if n.Children == nil {
+		n.Children = make(map[string][]*Node)
+	}
+	if n.Children[child.Name] == nil {
+		n.Children[child.Name] = make([]*Node, 0)
+		n.ChildOrder = append(n.ChildOrder, child.Name)
+	}
+	n.Children[child.Name] = append(n.Children[child.Name], child)
+}
+
+// RemoveChild removes a child node, returning false if it isn't a child of n
+func (n *Node) RemoveChild(child *Node) bool {
+	siblings := n.Children[child.Name]
+	for i, sibling := range siblings {
+		if sibling == child {
+			n.Children[child.Name] = append(siblings[:i:i], siblings[i+1:]...)
+			if len(n.Children[child.Name]) == 0 {
+				delete(n.Children, child.Name)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// SetAttribute adds or replaces an attribute, remembering the order attributes were added in
+func (n *Node) SetAttribute(name string, attr *NodeAttribute) {
+	if n.Attributes == nil {
+		n.Attributes = make(map[string]*NodeAttribute)
+	}
+	if _, exists := n.Attributes[name]; !exists {
+		n.AttributeOrder = append(n.AttributeOrder, name)
+	}
+	n.Attributes[name] = attr
+}
+
+// NodeAttribute represents an attribute of a node
+type NodeAttribute struct {
+	Type  AttributeType
+	Value interface{}
+	// Decodes Value for lazy attributes, until Load has been called
+	load func() (interface{}, error)
+}
+
+/*
+NewLazyAttribute makes an attribute whose Value is only decoded by load when Load is called, so files can be
+queried without decoding every value in them. ValueString, the Sorter, diff and the writers load attributes
+when they need their values, code reading Value directly has to call Load first.
+*/
+func NewLazyAttribute(attrType AttributeType, load func() (interface{}, error)) *NodeAttribute {
+	return &NodeAttribute{Type: attrType, load: load}
+}
+
+// Load decodes a lazy attribute's value into Value, it does nothing for other attributes or ones already loaded.
+// It isn't safe to load the same attribute from several goroutines at once.
+func (attr *NodeAttribute) Load() error {
+	if attr.load == nil {
+		return nil
+	}
+	value, err := attr.load()
+	if err != nil {
+		return err
+	}
+	attr.Value = value
+	attr.load = nil
+	return nil
+}
+
+// IsLoaded returns false for lazy attributes whose Value hasn't been decoded yet
+func (attr *NodeAttribute) IsLoaded() bool {
+	return attr.load == nil
+}
+
+// LoadValues loads every lazy attribute of the node and the nodes below it, to find values that fail to decode
+// before using them
+func (n *Node) LoadValues() error {
+	for _, name := range n.AttributeNames() {
+		err := n.Attributes[name].Load()
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range n.ChildNames() {
+		for _, child := range n.Children[name] {
+			err := child.LoadValues()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadValues loads every lazy attribute in the resource, see Node.LoadValues
+func (r *Resource) LoadValues() error {
+	for _, regionName := range r.RegionNames() {
+		err := r.Regions[regionName].LoadValues()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttributeType represents the type of an attribute
+type AttributeType uint32
+
+const (
+	AttrNone AttributeType = iota
+	AttrByte
+	AttrShort
+	AttrUShort
+	AttrInt
+	AttrUInt
+	AttrFloat
+	AttrDouble
+	AttrIVec2
+	AttrIVec3
+	AttrIVec4
+	AttrVec2
+	AttrVec3
+	AttrVec4
+	AttrMat2
+	AttrMat3
+	AttrMat3x4
+	AttrMat4x3
+	AttrMat4
+	AttrBool
+	AttrString
+	AttrPath
+	AttrFixedString
+	AttrLSString
+	AttrULongLong
+	AttrScratchBuffer
+	AttrLong
+	AttrInt8
+	AttrTranslatedString
+	AttrWString
+	AttrLSWString
+	AttrUUID
+	AttrInt64
+	AttrTranslatedFSString
+	AttrMax = AttrTranslatedFSString
+)
+
+// TranslatedString represents a translated string
+type TranslatedString struct {
+	Version uint16
+	Handle  string
+	Value   string
+}
+
+// TranslatedFSString represents a translated FS string
+type TranslatedFSString struct {
+	Version   uint16
+	Handle    string
+	Value     string
+	Arguments []TranslatedFSStringArgument
+}
+
+// TranslatedFSStringArgument represents an argument to a TranslatedFSString
+type TranslatedFSStringArgument struct {
+	Key    string
+	Value  string
+	String TranslatedFSString
+}
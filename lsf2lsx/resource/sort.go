@@ -0,0 +1,200 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// RegionNames returns the region names sorted, for deterministic output
+func (r *Resource) RegionNames() []string {
+	regionNames := make([]string, 0, len(r.Regions))
+	for regionName := range r.Regions {
+		regionNames = append(regionNames, regionName)
+	}
+	sort.Strings(regionNames)
+	return regionNames
+}
+
+// AttributeNames returns the attribute names sorted, for deterministic output
+func (n *Node) AttributeNames() []string {
+	attrNames := make([]string, 0, len(n.Attributes))
+	for attrName := range n.Attributes {
+		attrNames = append(attrNames, attrName)
+	}
+	sort.Strings(attrNames)
+	return attrNames
+}
+
+// ChildNames returns the child node names sorted, for deterministic output
+func (n *Node) ChildNames() []string {
+	childNames := make([]string, 0, len(n.Children))
+	for childName := range n.Children {
+		childNames = append(childNames, childName)
+	}
+	sort.Strings(childNames)
+	return childNames
+}
+
+/*
+Sorter puts same-named siblings in a deterministic order.
+
+Sorting by the whole node digest means editing one attribute can move a node somewhere else entirely, and
+the diff shows it as removed and re-added. So nodes that have a key are sorted by the key's value first,
+which keeps them in place when only their contents change. The key is the attribute configured for the
+node name in Keys (e.g. "GameObjects": "MapKey"), or failing that the node's own KeyAttribute. Nodes with
+the same key value, and nodes without a key (which go after the keyed ones), fall back to the Digest.
+
+Digests are cached for the lifetime of the Sorter, so nodes shouldn't change once they've been sorted or
+hashed with it (changing them further down the tree before sorting that part is fine). A Sorter isn't safe
+for concurrent use.
+*/
+type Sorter struct {
+	// Node name -> name of the attribute that identifies nodes of that name
+	Keys map[string]string
+
+	digests map[*Node]Digest
+}
+
+// Sort returns a copy of same-named sibling nodes in a deterministic order
+func (s *Sorter) Sort(nodes []*Node) []*Node {
+	sorted := make([]*Node, len(nodes))
+	copy(sorted, nodes)
+	if len(sorted) < 2 {
+		return sorted
+	}
+
+	type sortKey struct {
+		hasKey bool
+		key    string
+		digest Digest
+	}
+	keys := make(map[*Node]sortKey, len(sorted))
+	for _, node := range sorted {
+		key, hasKey := s.KeyValue(node)
+		keys[node] = sortKey{hasKey, key, s.Digest(node)}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := keys[sorted[i]], keys[sorted[j]]
+		if a.hasKey != b.hasKey {
+			return a.hasKey
+		}
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return bytes.Compare(a.digest[:], b.digest[:]) < 0
+	})
+	return sorted
+}
+
+// OrderSiblings returns same-named sibling nodes in the given order
+func (s *Sorter) OrderSiblings(nodes []*Node, order Order) []*Node {
+	if order == OrderFile {
+		return nodes
+	}
+	return s.Sort(nodes)
+}
+
+// KeyValue returns the value of the attribute identifying the node, if it has one
+func (s *Sorter) KeyValue(node *Node) (string, bool) {
+	keyName := s.keyName(node)
+	if keyName == "" {
+		return "", false
+	}
+
+	attr, ok := node.Attributes[keyName]
+	if !ok {
+		return "", false
+	}
+	return attr.ValueString(), true
+}
+
+// The configured key for the node's name, or the node's own key attribute
+func (s *Sorter) keyName(node *Node) string {
+	if keyName := s.Keys[node.Name]; keyName != "" {
+		return keyName
+	}
+	return node.KeyAttribute
+}
+
+// SortSiblings returns a copy of same-named sibling nodes in a deterministic order, using only the nodes' own keys
+func SortSiblings(nodes []*Node) []*Node {
+	return (&Sorter{}).Sort(nodes)
+}
+
+// Digest is a SHA-256 of a node's contents, see Sorter.Digest
+type Digest [sha256.Size]byte
+
+/*
+Digest returns a hash of the node's key attribute, attributes and children, which is the same for nodes
+with the same contents wherever they are in the tree. Same-named siblings are hashed in the order of their
+digests, so their order doesn't matter either.
+
+We need this cos we wanna diff the LSXs, and nodes being in a different order will flag changes that
+aren't actually changes. There's 2 ways nodes could get out of order in the data resource:
+  - The maps we use to store the nodes are an unordered data structure
+  - The actual binary gets out of order (The Divinity Engine might not write em deterministically)
+
+We can't just sort alphabetically because sibling nodes can have the same name (i.e. Object)). The
+simplest way to guarantee the same nodes are always in the same order is to hash the entire node
+(attributes + children) and sort by that.
+
+Each node is hashed once, from the digests of its children, so sorting a whole tree takes linear time
+however deep it is.
+*/
+func (s *Sorter) Digest(node *Node) Digest {
+	if digest, ok := s.digests[node]; ok {
+		return digest
+	}
+
+	hash := sha256.New()
+	writeHashString(hash, node.KeyAttribute)
+
+	attrNames := node.AttributeNames()
+	writeHashLength(hash, len(attrNames))
+	for _, attrName := range attrNames {
+		writeHashString(hash, attrName)
+		writeHashString(hash, node.Attributes[attrName].ValueString())
+	}
+
+	childNames := node.ChildNames()
+	writeHashLength(hash, len(childNames))
+	for _, childName := range childNames {
+		children := node.Children[childName]
+		childDigests := make([]Digest, len(children))
+		for i, child := range children {
+			childDigests[i] = s.Digest(child)
+		}
+		sort.Slice(childDigests, func(i, j int) bool {
+			return bytes.Compare(childDigests[i][:], childDigests[j][:]) < 0
+		})
+
+		writeHashString(hash, childName)
+		writeHashLength(hash, len(childDigests))
+		for _, childDigest := range childDigests {
+			hash.Write(childDigest[:])
+		}
+	}
+
+	var digest Digest
+	hash.Sum(digest[:0])
+	if s.digests == nil {
+		s.digests = make(map[*Node]Digest)
+	}
+	s.digests[node] = digest
+	return digest
+}
+
+// Strings are length prefixed, so no two different sequences of them hash the same
+func writeHashString(hash io.Writer, value string) {
+	writeHashLength(hash, len(value))
+	io.WriteString(hash, value)
+}
+
+func writeHashLength(hash io.Writer, length int) {
+	var buffer [binary.MaxVarintLen64]byte
+	hash.Write(buffer[:binary.PutUvarint(buffer[:], uint64(length))])
+}
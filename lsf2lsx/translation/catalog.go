@@ -0,0 +1,165 @@
+// Package translation collects the translated strings used by resources, for exchanging them with
+// translators as PO or XLIFF files and turning the translations back into .loca files.
+package translation
+
+import (
+	"sort"
+
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/loca"
+	"github.com/tom-bg3-modding/bg3-lsf2lsx-converter/lsf2lsx/resource"
+)
+
+// Unit is one translatable text, identified by its handle and version
+type Unit struct {
+	Handle  string
+	Version uint16
+	Source  string
+	// Empty when not translated yet
+	Target string
+	// Files using the handle
+	References []string
+}
+
+// Catalog holds the units of one language pair
+type Catalog struct {
+	// Language codes like "en" or "fr"
+	SourceLanguage string
+	TargetLanguage string
+	Units          []*Unit
+	handles        map[string]*Unit
+}
+
+func NewCatalog(sourceLanguage, targetLanguage string) *Catalog {
+	return &Catalog{
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		handles:        make(map[string]*Unit),
+	}
+}
+
+// Unit returns the unit of a handle
+func (c *Catalog) Unit(handle string) (*Unit, bool) {
+	unit, ok := c.handles[handle]
+	return unit, ok
+}
+
+// Add adds a unit, or returns the existing one for its handle
+func (c *Catalog) Add(handle string, version uint16) *Unit {
+	if c.handles == nil {
+		c.handles = make(map[string]*Unit)
+	}
+	unit, ok := c.handles[handle]
+	if !ok {
+		unit = &Unit{Handle: handle, Version: version}
+		c.handles[handle] = unit
+		c.Units = append(c.Units, unit)
+	}
+	if version > unit.Version {
+		unit.Version = version
+	}
+	return unit
+}
+
+// AddResource adds the handles of every TranslatedString and TranslatedFSString in a resource (including
+// the strings nested in FS string arguments). Reference names the file, for translators to find the context.
+func (c *Catalog) AddResource(res *resource.Resource, reference string) {
+	for _, regionName := range res.RegionNames() {
+		c.addNode(&res.Regions[regionName].Node, reference)
+	}
+}
+
+func (c *Catalog) addNode(node *resource.Node, reference string) {
+	for _, attrName := range node.AttributeNames() {
+		attr := node.Attributes[attrName]
+		// Lazy attributes that fail to decode have no handle to add
+		attr.Load()
+		switch value := attr.Value.(type) {
+		case *resource.TranslatedString:
+			c.addHandle(value.Handle, value.Version, reference)
+		case *resource.TranslatedFSString:
+			c.addFSString(value, reference)
+		}
+	}
+	for _, childName := range node.ChildNames() {
+		for _, child := range node.Children[childName] {
+			c.addNode(child, reference)
+		}
+	}
+}
+
+func (c *Catalog) addFSString(fs *resource.TranslatedFSString, reference string) {
+	c.addHandle(fs.Handle, fs.Version, reference)
+	for i := range fs.Arguments {
+		c.addFSString(&fs.Arguments[i].String, reference)
+	}
+}
+
+func (c *Catalog) addHandle(handle string, version uint16, reference string) {
+	// Empty handles and "ls::TranslatedStringRepository::s_HandleUnknown" don't point at any text
+	if handle == "" || handle == unknownHandle {
+		return
+	}
+	unit := c.Add(handle, version)
+	for _, existing := range unit.References {
+		if existing == reference {
+			return
+		}
+	}
+	unit.References = append(unit.References, reference)
+}
+
+const unknownHandle = "ls::TranslatedStringRepository::s_HandleUnknown"
+
+/*
+SetSources fills in the source texts from a localization file. Units that don't know their version (FS
+strings read from LSX, which doesn't store it) take the version of the entry.
+*/
+func (c *Catalog) SetSources(texts *loca.Resource) {
+	for _, entry := range texts.Entries {
+		if unit, ok := c.handles[entry.Key]; ok {
+			unit.Source = entry.Text
+			if unit.Version == 0 {
+				unit.Version = entry.Version
+			}
+		}
+	}
+}
+
+// SetTargets fills in existing translations from a localization file
+func (c *Catalog) SetTargets(texts *loca.Resource) {
+	for _, entry := range texts.Entries {
+		if unit, ok := c.handles[entry.Key]; ok {
+			unit.Target = entry.Text
+		}
+	}
+}
+
+// Sort sorts the units by handle, and their references by name
+func (c *Catalog) Sort() {
+	sort.Slice(c.Units, func(i, j int) bool {
+		return c.Units[i].Handle < c.Units[j].Handle
+	})
+	for _, unit := range c.Units {
+		sort.Strings(unit.References)
+	}
+}
+
+/*
+Loca returns the translations as a localization file, keeping the units' versions. Untranslated units get
+their source text, so the game shows something rather than nothing for them.
+*/
+func (c *Catalog) Loca() *loca.Resource {
+	res := &loca.Resource{Entries: make([]loca.Entry, 0, len(c.Units))}
+	for _, unit := range c.Units {
+		text := unit.Target
+		if text == "" {
+			text = unit.Source
+		}
+		version := unit.Version
+		if version == 0 {
+			version = 1
+		}
+		res.Entries = append(res.Entries, loca.Entry{Key: unit.Handle, Version: version, Text: text})
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package translation
+
+import "strings"
+
+// Localization folder names the game uses, by language code
+var languageFolders = map[string]string{
+	"en":     "English",
+	"fr":     "French",
+	"de":     "German",
+	"es":     "Spanish",
+	"es-mx":  "LatinSpanish",
+	"es-419": "LatinSpanish",
+	"it":     "Italian",
+	"pl":     "Polish",
+	"ru":     "Russian",
+	"uk":     "Ukrainian",
+	"tr":     "Turkish",
+	"pt-br":  "BrazilianPortuguese",
+	"zh":     "Chinese",
+	"zh-cn":  "Chinese",
+	"zh-tw":  "ChineseTraditional",
+	"ja":     "Japanese",
+	"ko":     "Korean",
+}
+
+// LanguageFolder returns the Localization folder name of a language, given as a code ("fr", "pt_BR") or
+// the folder name itself
+func LanguageFolder(language string) (string, bool) {
+	code := strings.ReplaceAll(strings.ToLower(language), "_", "-")
+	if folder, ok := languageFolders[code]; ok {
+		return folder, true
+	}
+	for _, folder := range languageFolders {
+		if strings.EqualFold(folder, language) {
+			return folder, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,226 @@
+package translation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+WritePO writes the catalog as a gettext PO file. Every unit is its own message, with "handle;version" as
+the context, so texts that happen to be the same in the source language can still be translated apart:
+
+	#: Public/MyMod/RootTemplates/Sword.lsf
+	msgctxt "h3b1c...;1"
+	msgid "Longsword"
+	msgstr "Épée longue"
+*/
+func WritePO(w io.Writer, catalog *Catalog) error {
+	out := bufio.NewWriter(w)
+
+	out.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	out.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	if catalog.TargetLanguage != "" {
+		fmt.Fprintf(out, "\"Language: %s\\n\"\n", poEscaper.Replace(catalog.TargetLanguage))
+	}
+	if catalog.SourceLanguage != "" {
+		fmt.Fprintf(out, "\"X-Source-Language: %s\\n\"\n", poEscaper.Replace(catalog.SourceLanguage))
+	}
+
+	for _, unit := range catalog.Units {
+		out.WriteString("\n")
+		for _, reference := range unit.References {
+			fmt.Fprintf(out, "#: %s\n", reference)
+		}
+		writePOString(out, "msgctxt", unitID(unit))
+		writePOString(out, "msgid", unit.Source)
+		writePOString(out, "msgstr", unit.Target)
+	}
+
+	return out.Flush()
+}
+
+// Multi-line texts are split after each line break, like gettext does
+func writePOString(out *bufio.Writer, keyword string, text string) {
+	if !strings.Contains(strings.TrimSuffix(text, "\n"), "\n") {
+		fmt.Fprintf(out, "%s \"%s\"\n", keyword, poEscaper.Replace(text))
+		return
+	}
+	fmt.Fprintf(out, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line != "" {
+			fmt.Fprintf(out, "\"%s\"\n", poEscaper.Replace(line))
+		}
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+type poMessage struct {
+	context    string
+	id         string
+	str        string
+	hasContext bool
+	hasStr     bool
+	fuzzy      bool
+	references []string
+}
+
+/*
+ReadPO reads a PO file written by WritePO (and edited by a translator). Fuzzy translations are treated as
+untranslated, as gettext does. Messages have to keep their "handle;version" context.
+*/
+func ReadPO(r io.Reader) (*Catalog, error) {
+	catalog := NewCatalog("", "")
+	message := &poMessage{}
+	var field *string
+	lineNumber := 0
+
+	flush := func() error {
+		defer func() {
+			message = &poMessage{}
+			field = nil
+		}()
+		if !message.hasStr {
+			return nil
+		}
+		if !message.hasContext && message.id == "" {
+			readPOHeader(catalog, message.str)
+			return nil
+		}
+		if !message.hasContext {
+			return fmt.Errorf("line %d: message %q has no msgctxt with its handle", lineNumber, message.id)
+		}
+		handle, version, err := parseUnitID(message.context)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if _, exists := catalog.Unit(handle); exists {
+			return fmt.Errorf("line %d: handle %s is listed twice", lineNumber, handle)
+		}
+		unit := catalog.Add(handle, version)
+		unit.Source = message.id
+		if !message.fuzzy {
+			unit.Target = message.str
+		}
+		unit.References = message.references
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		// A new message can start without a blank line after the previous one, with its comments or msgctxt
+		if message.hasStr && strings.HasPrefix(line, "#") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+		case strings.HasPrefix(line, "#:"):
+			message.references = append(message.references, strings.Fields(line[2:])...)
+
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(line[2:], ",") {
+				if strings.TrimSpace(flag) == "fuzzy" {
+					message.fuzzy = true
+				}
+			}
+
+		case strings.HasPrefix(line, "#"):
+			// Other comments and obsolete messages
+
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("line %d: string outside of a message", lineNumber)
+			}
+			text, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid string %s", lineNumber, line)
+			}
+			*field += text
+
+		default:
+			keyword, value, _ := strings.Cut(line, " ")
+			if message.hasStr && (keyword == "msgctxt" || keyword == "msgid") {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			switch keyword {
+			case "msgctxt":
+				field = &message.context
+				message.hasContext = true
+			case "msgid":
+				field = &message.id
+			case "msgstr":
+				field = &message.str
+				message.hasStr = true
+			case "msgid_plural":
+				return nil, fmt.Errorf("line %d: plural forms are not supported", lineNumber)
+			default:
+				return nil, fmt.Errorf("line %d: unknown keyword %q", lineNumber, keyword)
+			}
+			text, err := strconv.Unquote(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid string %s", lineNumber, value)
+			}
+			*field = text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// The header is "Name: value" lines in the msgstr of the message with an empty msgid
+func readPOHeader(catalog *Catalog, header string) {
+	for _, line := range strings.Split(header, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "Language":
+			catalog.TargetLanguage = strings.TrimSpace(value)
+		case "X-Source-Language":
+			catalog.SourceLanguage = strings.TrimSpace(value)
+		}
+	}
+}
+
+// "handle;version", the same form diff uses for translated strings
+func unitID(unit *Unit) string {
+	return fmt.Sprintf("%s;%d", unit.Handle, unit.Version)
+}
+
+func parseUnitID(id string) (string, uint16, error) {
+	handle, versionText, ok := strings.Cut(id, ";")
+	if !ok || handle == "" {
+		return "", 0, fmt.Errorf("invalid handle %q (expected handle;version)", id)
+	}
+	version, err := strconv.ParseUint(versionText, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid version in %q", id)
+	}
+	return handle, uint16(version), nil
+}
@@ -0,0 +1,118 @@
+package translation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string           `xml:"original,attr"`
+	SourceLanguage string           `xml:"source-language,attr"`
+	TargetLanguage string           `xml:"target-language,attr,omitempty"`
+	Datatype       string           `xml:"datatype,attr"`
+	Units          []xliffTransUnit `xml:"body>trans-unit"`
+}
+
+type xliffTransUnit struct {
+	ID      string       `xml:"id,attr"`
+	ResName string       `xml:"resname,attr,omitempty"`
+	Source  string       `xml:"source"`
+	Target  *xliffTarget `xml:"target"`
+	Notes   []xliffNote  `xml:"note"`
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+type xliffNote struct {
+	From string `xml:"from,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+/*
+WriteXLIFF writes the catalog as XLIFF 1.2. Units are identified by "handle;version" like in PO files, and
+the files using a handle are listed as notes.
+*/
+func WriteXLIFF(w io.Writer, catalog *Catalog) error {
+	file := xliffFile{
+		Original:       "localization",
+		SourceLanguage: catalog.SourceLanguage,
+		TargetLanguage: catalog.TargetLanguage,
+		Datatype:       "plaintext",
+		Units:          make([]xliffTransUnit, 0, len(catalog.Units)),
+	}
+	for _, unit := range catalog.Units {
+		transUnit := xliffTransUnit{ID: unitID(unit), ResName: unit.Handle, Source: unit.Source}
+		if unit.Target != "" {
+			transUnit.Target = &xliffTarget{State: "translated", Text: unit.Target}
+		}
+		for _, reference := range unit.References {
+			transUnit.Notes = append(transUnit.Notes, xliffNote{From: "reference", Text: reference})
+		}
+		file.Units = append(file.Units, transUnit)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	err = encoder.Encode(xliffDocument{Version: "1.2", Files: []xliffFile{file}})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// ReadXLIFF reads an XLIFF 1.2 file. Targets still marked as needing a translation count as untranslated.
+func ReadXLIFF(r io.Reader) (*Catalog, error) {
+	var document xliffDocument
+	err := xml.NewDecoder(r).Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLIFF document: %w", err)
+	}
+
+	catalog := NewCatalog("", "")
+	for _, file := range document.Files {
+		if catalog.SourceLanguage == "" {
+			catalog.SourceLanguage = file.SourceLanguage
+		}
+		if catalog.TargetLanguage == "" {
+			catalog.TargetLanguage = file.TargetLanguage
+		}
+
+		for _, transUnit := range file.Units {
+			handle, version, err := parseUnitID(transUnit.ID)
+			if err != nil {
+				return nil, fmt.Errorf("trans-unit %q: %w", transUnit.ID, err)
+			}
+			if _, exists := catalog.Unit(handle); exists {
+				return nil, fmt.Errorf("trans-unit %q: handle %s is listed twice", transUnit.ID, handle)
+			}
+
+			unit := catalog.Add(handle, version)
+			unit.Source = transUnit.Source
+			if transUnit.Target != nil && transUnit.Target.State != "new" && transUnit.Target.State != "needs-translation" {
+				unit.Target = transUnit.Target.Text
+			}
+			for _, note := range transUnit.Notes {
+				if note.From == "reference" {
+					unit.References = append(unit.References, note.Text)
+				}
+			}
+		}
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+fileWatcher reports paths below the watched folders that changed: files that were written, created, moved
+or deleted, and folders whose contents changed without a report for each file in them (new folders, or
+events the system dropped). Hidden files and folders are left out.
+
+Changes is closed when the watcher stops, Errors reports problems that don't stop it.
+*/
+type fileWatcher interface {
+	Changes() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// Returned by newNativeWatcher on systems without file notifications, where polling is the only option
+var errNoNativeWatcher = errors.New("file notifications aren't supported on this system")
+
+// What polling compares to notice a change
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollWatcher scans the folders every interval and compares modification times and sizes
+type pollWatcher struct {
+	roots     []string
+	interval  time.Duration
+	files     map[string]fileState
+	changes   chan string
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPollWatcher(roots []string, interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		roots:    roots,
+		interval: interval,
+		changes:  make(chan string, 64),
+		errors:   make(chan error, 16),
+		done:     make(chan struct{}),
+	}
+	w.files = w.scan()
+	go w.run()
+	return w
+}
+
+func (w *pollWatcher) Changes() <-chan string { return w.changes }
+func (w *pollWatcher) Errors() <-chan error   { return w.errors }
+
+func (w *pollWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return nil
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.changes)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+
+		files := w.scan()
+		for path, state := range files {
+			if old, ok := w.files[path]; !ok || old != state {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		for path := range w.files {
+			if _, ok := files[path]; !ok {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		w.files = files
+	}
+}
+
+func (w *pollWatcher) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	for _, root := range w.roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				w.sendError(err)
+				return nil
+			}
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				// Deleted while scanning
+				return nil
+			}
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			w.sendError(err)
+		}
+	}
+	return files
+}
+
+// Returns false once the watcher is closed
+func (w *pollWatcher) send(path string) bool {
+	select {
+	case w.changes <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Errors are dropped rather than holding up the scan when nobody reads them
+func (w *pollWatcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,215 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Writes, creates, deletes and both halves of a move. Editors that save through a temporary file show up as a move,
+// and folders moved out of the tree only as the first half.
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+/*
+inotifyWatcher watches every folder below the roots with inotify, adding folders as they're created.
+
+The descriptor is non-blocking and wrapped in an os.File, so reads go through the runtime poller and Close
+wakes up the reading goroutine.
+*/
+type inotifyWatcher struct {
+	file  *os.File
+	fd    int
+	roots []string
+	// Watch descriptor -> folder, only used by the reading goroutine once it's started
+	watches   map[int32]string
+	changes   chan string
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newNativeWatcher(roots []string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+
+	w := &inotifyWatcher{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		roots:   roots,
+		watches: make(map[int32]string),
+		changes: make(chan string, 64),
+		errors:  make(chan error, 16),
+		done:    make(chan struct{}),
+	}
+	for _, root := range roots {
+		err = w.addTree(root)
+		if err != nil {
+			w.file.Close()
+			return nil, err
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Changes() <-chan string { return w.changes }
+func (w *inotifyWatcher) Errors() <-chan error   { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
+
+// Watches a folder and the folders below it, apart from hidden ones
+func (w *inotifyWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Folders can disappear again before they're watched
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask|syscall.IN_ONLYDIR)
+		if err != nil {
+			if err == syscall.ENOSPC {
+				return fmt.Errorf("inotify: can't watch %s, the limit of watched folders was reached (fs.inotify.max_user_watches): %w", path, err)
+			}
+			return fmt.Errorf("inotify: can't watch %s: %w", path, err)
+		}
+		w.watches[int32(wd)] = path
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) run() {
+	defer close(w.changes)
+
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buffer)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.sendError(fmt.Errorf("inotify: %w", err))
+			}
+			return
+		}
+
+		// Events are a fixed size header followed by a null padded name
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			var event syscall.InotifyEvent
+			binary.Read(bytes.NewReader(buffer[offset:offset+syscall.SizeofInotifyEvent]), binary.NativeEndian, &event)
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			if nameEnd > n {
+				break
+			}
+			name := nullTerminated(buffer[nameStart:nameEnd])
+			offset = nameEnd
+
+			if !w.handle(event, name) {
+				return
+			}
+		}
+	}
+}
+
+// Returns false once the watcher is closed
+func (w *inotifyWatcher) handle(event syscall.InotifyEvent, name string) bool {
+	if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		// Events were dropped, so anything may have changed
+		w.sendError(fmt.Errorf("inotify: too many changes at once, rescanning"))
+		for _, root := range w.roots {
+			if !w.send(root) {
+				return false
+			}
+		}
+		return true
+	}
+	if event.Mask&syscall.IN_IGNORED != 0 {
+		delete(w.watches, event.Wd)
+		return true
+	}
+
+	dir, ok := w.watches[event.Wd]
+	if !ok || name == "" || strings.HasPrefix(name, ".") {
+		return true
+	}
+	path := filepath.Join(dir, name)
+
+	if event.Mask&syscall.IN_ISDIR != 0 {
+		switch {
+		case event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			// New folders may have files already, which get no events
+			err := w.addTree(path)
+			if err != nil {
+				w.sendError(err)
+			}
+		case event.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+			// A folder moved away is still watched, but its events would name it by its old path
+			w.removeTree(path)
+		default:
+			return true
+		}
+	}
+	return w.send(path)
+}
+
+// Stops watching a folder and the folders below it
+func (w *inotifyWatcher) removeTree(root string) {
+	for wd, dir := range w.watches {
+		if isUnder(dir, root) {
+			// Fails for deleted folders, whose watches are already gone
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, wd)
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.changes <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *inotifyWatcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func nullTerminated(name []byte) string {
+	if end := bytes.IndexByte(name, 0); end != -1 {
+		name = name[:end]
+	}
+	return string(name)
+}
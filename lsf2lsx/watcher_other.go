@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func newNativeWatcher(roots []string) (fileWatcher, error) {
+	return nil, errNoNativeWatcher
+}